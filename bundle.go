@@ -0,0 +1,231 @@
+package certdeck
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnexpectedBlock   = errors.New("unexpected block in bundle")
+	ErrUnsupportedBundle = errors.New("unsupported bundle format")
+)
+
+// BundleFormat is the encoding detected by DecodeBundle.
+type BundleFormat int
+
+const (
+	// BundleFormatPEM is a sequence of PEM blocks, possibly mixed with text.
+	BundleFormatPEM BundleFormat = iota
+	// BundleFormatDER is a single DER structure, or a sequence of DER certificates.
+	BundleFormatDER
+	// BundleFormatBase64 is a DER structure, encoded in standard base64 without PEM armor.
+	BundleFormatBase64
+	// BundleFormatPKCS7 is a DER PKCS#7 SignedData structure. PKCS#7 structures inside PEM or base64 input are
+	// reported with the outer format.
+	BundleFormatPKCS7
+)
+
+// Bundle is the content of a file decoded by DecodeBundle. Each list keeps the order of the input.
+type Bundle struct {
+	Format BundleFormat
+
+	Certificates []*x509.Certificate
+	Keys         []crypto.Signer
+	CSRs         []*x509.CertificateRequest
+	CRLs         []*x509.RevocationList
+
+	// Unknown holds the PEM blocks that could not be decoded, with their headers. It is always empty when
+	// BundleOptions.Strict is set.
+	Unknown []*pem.Block
+}
+
+// BundleOptions configures DecodeBundleWithOptions.
+type BundleOptions struct {
+	// Strict fails with ErrUnexpectedBlock on PEM blocks of an unknown type, instead of adding them to
+	// Bundle.Unknown.
+	Strict bool
+
+	// Passphrase decrypts encrypted private keys. If nil, encrypted keys are treated as unexpected blocks.
+	Passphrase []byte
+}
+
+func (bundle *Bundle) addPKCS7(data []byte) error {
+	certs, crls, err := parsePKCS7(data)
+	if err != nil {
+		return err
+	}
+
+	bundle.Certificates = append(bundle.Certificates, certs...)
+	bundle.CRLs = append(bundle.CRLs, crls...)
+
+	return nil
+}
+
+func (bundle *Bundle) addUnexpected(block *pem.Block, opts *BundleOptions, reason error) error {
+	if opts.Strict {
+		return fmt.Errorf("%w: %w", ErrUnexpectedBlock, reason)
+	}
+
+	bundle.Unknown = append(bundle.Unknown, block)
+
+	return nil
+}
+
+func (bundle *Bundle) addPEMBlock(block *pem.Block, opts *BundleOptions) error {
+	//nolint:staticcheck // Only used to detect legacy encrypted blocks.
+	if block.Type == pemTypeEncryptedPKCS8 || x509.IsEncryptedPEMBlock(block) {
+		if opts.Passphrase == nil {
+			return bundle.addUnexpected(block, opts, fmt.Errorf("%s: %w", block.Type, ErrEncryptedKey))
+		}
+
+		key, err := encryptedPEMBlockToKey(block, opts.Passphrase)
+		if err != nil {
+			return fmt.Errorf("decrypt %s: %w", block.Type, err)
+		}
+
+		bundle.Keys = append(bundle.Keys, key)
+
+		return nil
+	}
+
+	switch block.Type {
+	case pemTypeCertificate:
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("parse certificate: %w", err)
+		}
+
+		bundle.Certificates = append(bundle.Certificates, cert)
+	case pemTypePKCS1, pemTypeSEC1, pemTypePKCS8:
+		key, err := pemBlockToKey(block)
+		if err != nil {
+			return err
+		}
+
+		bundle.Keys = append(bundle.Keys, key)
+	case pemTypeCSR, pemTypeCSRLegacy:
+		csr, err := DERToCSR(block.Bytes)
+		if err != nil {
+			return err
+		}
+
+		bundle.CSRs = append(bundle.CSRs, csr)
+	case pemTypeCRL:
+		crl, err := DERToCRL(block.Bytes)
+		if err != nil {
+			return err
+		}
+
+		bundle.CRLs = append(bundle.CRLs, crl)
+	case pemTypePKCS7:
+		return bundle.addPKCS7(block.Bytes)
+	default:
+		return bundle.addUnexpected(block, opts, fmt.Errorf("type %s", block.Type))
+	}
+
+	return nil
+}
+
+// addDER detects the content of a DER structure.
+func (bundle *Bundle) addDER(data []byte) error {
+	if isPKCS7(data) {
+		return bundle.addPKCS7(data)
+	}
+
+	if certs, err := x509.ParseCertificates(data); err == nil {
+		bundle.Certificates = append(bundle.Certificates, certs...)
+		return nil
+	}
+
+	if crl, err := x509.ParseRevocationList(data); err == nil {
+		bundle.CRLs = append(bundle.CRLs, crl)
+		return nil
+	}
+
+	if csr, err := x509.ParseCertificateRequest(data); err == nil {
+		bundle.CSRs = append(bundle.CSRs, csr)
+		return nil
+	}
+
+	if key, err := DERToKey(data); err == nil {
+		bundle.Keys = append(bundle.Keys, key)
+		return nil
+	}
+
+	return fmt.Errorf("%w: no known DER structure", ErrUnsupportedBundle)
+}
+
+// decodeBase64 decodes data as standard base64, ignoring whitespace and line breaks.
+func decodeBase64(data []byte) ([]byte, bool) {
+	compact := bytes.Join(bytes.Fields(data), nil)
+	if len(compact) == 0 {
+		return nil, false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(string(compact))
+	if err != nil {
+		return nil, false
+	}
+
+	return decoded, true
+}
+
+// DecodeBundle decodes every certificate, private key, CSR and revocation list of a file. PEM blocks of unknown
+// types are returned in Bundle.Unknown. See DecodeBundleWithOptions.
+func DecodeBundle(data []byte) (*Bundle, error) {
+	return DecodeBundleWithOptions(data, nil)
+}
+
+// DecodeBundleWithOptions decodes every certificate, private key, CSR and revocation list of a file.
+//
+// The encoding is detected automatically:
+//   - PEM input may mix any number of blocks, like a full chain followed by its key. Text outside the blocks is
+//     ignored.
+//   - Base64 input, without PEM armor, is decoded then read as DER.
+//   - DER input may be a PKCS#7 SignedData structure, a sequence of certificates, a revocation list, a CSR or a
+//     private key.
+//
+// If opts is nil, the default options are used.
+func DecodeBundleWithOptions(data []byte, opts *BundleOptions) (*Bundle, error) {
+	if opts == nil {
+		opts = &BundleOptions{}
+	}
+
+	bundle := &Bundle{}
+
+	block, rest := pem.Decode(data)
+	if block != nil {
+		bundle.Format = BundleFormatPEM
+
+		for ; block != nil; block, rest = pem.Decode(rest) {
+			if err := bundle.addPEMBlock(block, opts); err != nil {
+				return nil, err
+			}
+		}
+
+		return bundle, nil
+	}
+
+	if decoded, ok := decodeBase64(data); ok {
+		if err := bundle.addDER(decoded); err == nil {
+			bundle.Format = BundleFormatBase64
+			return bundle, nil
+		}
+	}
+
+	if err := bundle.addDER(data); err != nil {
+		return nil, err
+	}
+
+	bundle.Format = BundleFormatDER
+	if isPKCS7(data) {
+		bundle.Format = BundleFormatPKCS7
+	}
+
+	return bundle, nil
+}
@@ -0,0 +1,178 @@
+package certdeck_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	testcerts "github.com/a-novel-kit/certdeck/internal/certs"
+)
+
+func TestDecodeBundle(t *testing.T) {
+	chain := []*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "bundle ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	caRaw, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, key.Public(), key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caRaw)
+	require.NoError(t, err)
+
+	crlRaw, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, ca, key)
+	require.NoError(t, err)
+
+	csrRaw, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "bundle csr"},
+	}, key)
+	require.NoError(t, err)
+
+	keyPEM, err := certdeck.KeyToPEM(key)
+	require.NoError(t, err)
+
+	unknown := &pem.Block{Type: "FOO", Headers: map[string]string{"Foo": "bar"}, Bytes: []byte("foo")}
+
+	// A typical fullchain+key file, with a few extra blocks and some text between them.
+	fullchain := bytes.Join([][]byte{
+		[]byte("Bag Attributes\n    friendlyName: leaf\n"),
+		certdeck.CertsToPEMInline(chain...),
+		testcerts.Chain1KeypairPEM,
+		pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crlRaw}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrRaw}),
+		pem.EncodeToMemory(unknown),
+		keyPEM,
+	}, nil)
+
+	bundle, err := certdeck.DecodeBundle(fullchain)
+	require.NoError(t, err)
+	require.Equal(t, certdeck.BundleFormatPEM, bundle.Format)
+	require.NoError(t, certdeck.Match(chain, bundle.Certificates))
+	require.Len(t, bundle.Keys, 2)
+	require.True(t, testcerts.Chain1Key.(*rsa.PrivateKey).Equal(bundle.Keys[0]))
+	require.True(t, key.Equal(bundle.Keys[1]))
+	require.Len(t, bundle.CRLs, 1)
+	require.Equal(t, crlRaw, bundle.CRLs[0].Raw)
+	require.Len(t, bundle.CSRs, 1)
+	require.Equal(t, csrRaw, bundle.CSRs[0].Raw)
+	require.Equal(t, []*pem.Block{unknown}, bundle.Unknown)
+
+	_, err = certdeck.DecodeBundleWithOptions(fullchain, &certdeck.BundleOptions{Strict: true})
+	require.ErrorIs(t, err, certdeck.ErrUnexpectedBlock)
+}
+
+func TestDecodeBundleFormats(t *testing.T) {
+	chain := []*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert}
+
+	testCases := []struct {
+		name string
+
+		data []byte
+
+		expectFormat certdeck.BundleFormat
+	}{
+		{
+			name:         "PEM",
+			data:         certdeck.CertsToPEMInline(chain...),
+			expectFormat: certdeck.BundleFormatPEM,
+		},
+		{
+			name:         "DER",
+			data:         certdeck.CertsToDERInline(chain...),
+			expectFormat: certdeck.BundleFormatDER,
+		},
+		{
+			name:         "Base64",
+			data:         []byte(base64.StdEncoding.EncodeToString(certdeck.CertsToDERInline(chain...))),
+			expectFormat: certdeck.BundleFormatBase64,
+		},
+		{
+			name:         "PKCS7",
+			data:         testcerts.ChainP7BDER,
+			expectFormat: certdeck.BundleFormatPKCS7,
+		},
+		{
+			name:         "PKCS7/PEM",
+			data:         testcerts.ChainP7BPEM,
+			expectFormat: certdeck.BundleFormatPEM,
+		},
+		{
+			name:         "PKCS7/Base64",
+			data:         []byte(base64.StdEncoding.EncodeToString(testcerts.ChainP7BDER)),
+			expectFormat: certdeck.BundleFormatBase64,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			bundle, err := certdeck.DecodeBundle(testCase.data)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectFormat, bundle.Format)
+			require.NoError(t, certdeck.Match(chain, bundle.Certificates))
+			require.Empty(t, bundle.Keys)
+			require.Empty(t, bundle.Unknown)
+		})
+	}
+
+	t.Run("Key/DER", func(t *testing.T) {
+		bundle, err := certdeck.DecodeBundle(testcerts.Chain1KeyDER)
+		require.NoError(t, err)
+		require.Equal(t, certdeck.BundleFormatDER, bundle.Format)
+		require.Len(t, bundle.Keys, 1)
+		require.True(t, testcerts.Chain1Key.(*rsa.PrivateKey).Equal(bundle.Keys[0]))
+	})
+
+	t.Run("Garbage", func(t *testing.T) {
+		_, err := certdeck.DecodeBundle([]byte("foo bar"))
+		require.ErrorIs(t, err, certdeck.ErrUnsupportedBundle)
+	})
+}
+
+func TestDecodeBundleEncryptedKey(t *testing.T) {
+	data := append(certdeck.CertsToPEMInline(testcerts.Chain1Cert), testcerts.EncryptedChain1KeyPBKDF2PEM...)
+
+	// Without a passphrase, the key is an unexpected block.
+	bundle, err := certdeck.DecodeBundle(data)
+	require.NoError(t, err)
+	require.Empty(t, bundle.Keys)
+	require.Len(t, bundle.Unknown, 1)
+	require.Equal(t, "ENCRYPTED PRIVATE KEY", bundle.Unknown[0].Type)
+
+	_, err = certdeck.DecodeBundleWithOptions(data, &certdeck.BundleOptions{Strict: true})
+	require.ErrorIs(t, err, certdeck.ErrUnexpectedBlock)
+	require.ErrorIs(t, err, certdeck.ErrEncryptedKey)
+
+	bundle, err = certdeck.DecodeBundleWithOptions(data, &certdeck.BundleOptions{
+		Passphrase: []byte(testcerts.EncryptedKeyPassphrase),
+	})
+	require.NoError(t, err)
+	require.Len(t, bundle.Keys, 1)
+	require.True(t, testcerts.Chain1Key.(*rsa.PrivateKey).Equal(bundle.Keys[0]))
+
+	_, err = certdeck.DecodeBundleWithOptions(data, &certdeck.BundleOptions{Passphrase: []byte("wrong")})
+	require.ErrorIs(t, err, certdeck.ErrIncorrectPassphrase)
+}
@@ -0,0 +1,135 @@
+package certdeck
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnusedCertificates = errors.New("certificates are not part of the chain")
+
+// MissingIssuer describes the issuer of the last certificate of a chain, when it could not be found.
+type MissingIssuer struct {
+	// Name is the issuer name of the last certificate.
+	Name pkix.Name
+	// AuthorityKeyID is the authority key identifier of the last certificate, if any.
+	AuthorityKeyID []byte
+}
+
+// ChainReport is the result of BuildChain.
+type ChainReport struct {
+	// Chain holds the linked certificates, leaf first.
+	Chain []*x509.Certificate
+	// Unused holds the certificates of the pool that are not part of the chain, in their original order.
+	Unused []*x509.Certificate
+	// MissingIssuer is set when the last certificate of the chain is not self-signed, and its issuer is not in
+	// the pool. This is expected for chains that do not include their root.
+	MissingIssuer *MissingIssuer
+}
+
+// Complete reports whether the chain ends with a self-signed certificate.
+func (report *ChainReport) Complete() bool {
+	return report.MissingIssuer == nil
+}
+
+// isIssuer checks whether parent issued child. Identifiers and names are compared first, then the signature of
+// child is checked against the key of parent.
+func isIssuer(child, parent *x509.Certificate) bool {
+	if !bytes.Equal(child.RawIssuer, parent.RawSubject) {
+		return false
+	}
+
+	if len(child.AuthorityKeyId) > 0 && len(parent.SubjectKeyId) > 0 &&
+		!bytes.Equal(child.AuthorityKeyId, parent.SubjectKeyId) {
+		return false
+	}
+
+	// The CA flag is not checked here, so a misconfigured chain is still ordered. Use VerifyRow to validate it.
+	return parent.CheckSignature(child.SignatureAlgorithm, child.RawTBSCertificate, child.Signature) == nil
+}
+
+// BuildChain links the certificates of the pool to the leaf, by authority and subject key identifiers, issuer and
+// subject names, and signatures. The chain stops at the first self-signed certificate, or when no issuer can be
+// found.
+//
+// The pool may contain the leaf itself, and certificates in any order. Duplicates of certificates already in the
+// chain are ignored.
+func BuildChain(leaf *x509.Certificate, pool []*x509.Certificate) *ChainReport {
+	report := &ChainReport{Chain: []*x509.Certificate{leaf}}
+
+	remaining := make([]*x509.Certificate, 0, len(pool))
+	for _, cert := range pool {
+		if !cert.Equal(leaf) {
+			remaining = append(remaining, cert)
+		}
+	}
+
+	current := leaf
+
+	for !isIssuer(current, current) {
+		found := -1
+
+		for pos, candidate := range remaining {
+			if isIssuer(current, candidate) {
+				found = pos
+				break
+			}
+		}
+
+		if found < 0 {
+			report.MissingIssuer = &MissingIssuer{Name: current.Issuer, AuthorityKeyID: current.AuthorityKeyId}
+			break
+		}
+
+		current = remaining[found]
+		report.Chain = append(report.Chain, current)
+
+		// Drop the issuer, and any duplicate of it.
+		kept := remaining[:0]
+		for _, cert := range remaining {
+			if !cert.Equal(current) {
+				kept = append(kept, cert)
+			}
+		}
+
+		remaining = kept
+	}
+
+	report.Unused = remaining
+
+	return report
+}
+
+// OrderChain finds the leaf matching the key, and orders the other certificates after it. The key can either be a
+// public key, or a crypto.Signer.
+//
+// It fails if some certificates do not belong to the chain. A missing root is not an error.
+func OrderChain(key any, certs []*x509.Certificate) ([]*x509.Certificate, error) {
+	var leaf *x509.Certificate
+
+	for _, cert := range certs {
+		if MatchKey(key, []*x509.Certificate{cert}) == nil {
+			leaf = cert
+			break
+		}
+	}
+
+	if leaf == nil {
+		return nil, fmt.Errorf("%w: no certificate matches the key", ErrCertKeyMismatch)
+	}
+
+	report := BuildChain(leaf, certs)
+	if len(report.Unused) > 0 {
+		names := make([]string, len(report.Unused))
+		for pos, cert := range report.Unused {
+			names[pos] = fmt.Sprintf("%s (serial %s)", cert.Subject, cert.SerialNumber)
+		}
+
+		return nil, fmt.Errorf("%w: %s", ErrUnusedCertificates, strings.Join(names, ", "))
+	}
+
+	return report.Chain, nil
+}
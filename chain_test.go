@@ -0,0 +1,113 @@
+package certdeck_test
+
+import (
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	testcerts "github.com/a-novel-kit/certdeck/internal/certs"
+)
+
+func TestBuildChain(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		leaf *x509.Certificate
+		pool []*x509.Certificate
+
+		expectChain    []*x509.Certificate
+		expectUnused   []*x509.Certificate
+		expectComplete bool
+	}{
+		{
+			name: "Ordered",
+			leaf: testcerts.Chain1Cert,
+			pool: []*x509.Certificate{
+				testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert, testcerts.CACert,
+			},
+			expectChain: []*x509.Certificate{
+				testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert, testcerts.CACert,
+			},
+			expectComplete: true,
+		},
+		{
+			name: "Shuffled",
+			leaf: testcerts.Chain1Cert,
+			pool: []*x509.Certificate{
+				testcerts.CACert, testcerts.Chain3Cert, testcerts.Chain1Cert, testcerts.Chain2Cert,
+			},
+			expectChain: []*x509.Certificate{
+				testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert, testcerts.CACert,
+			},
+			expectComplete: true,
+		},
+		{
+			name: "NoRoot",
+			leaf: testcerts.Chain1Cert,
+			pool: []*x509.Certificate{testcerts.Chain3Cert, testcerts.Chain2Cert},
+			expectChain: []*x509.Certificate{
+				testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert,
+			},
+		},
+		{
+			name:         "MissingIntermediate",
+			leaf:         testcerts.Chain1Cert,
+			pool:         []*x509.Certificate{testcerts.CACert, testcerts.Chain3Cert},
+			expectChain:  []*x509.Certificate{testcerts.Chain1Cert},
+			expectUnused: []*x509.Certificate{testcerts.CACert, testcerts.Chain3Cert},
+		},
+		{
+			name: "Duplicates",
+			leaf: testcerts.Chain2Cert,
+			pool: []*x509.Certificate{
+				testcerts.Chain3Cert, testcerts.Chain2Cert, testcerts.Chain3Cert, testcerts.Chain1Cert,
+			},
+			expectChain:  []*x509.Certificate{testcerts.Chain2Cert, testcerts.Chain3Cert},
+			expectUnused: []*x509.Certificate{testcerts.Chain1Cert},
+		},
+		{
+			name:           "SelfSigned",
+			leaf:           testcerts.CACert,
+			expectChain:    []*x509.Certificate{testcerts.CACert},
+			expectComplete: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			report := certdeck.BuildChain(testCase.leaf, testCase.pool)
+			require.NoError(t, certdeck.Match(testCase.expectChain, report.Chain))
+			require.Equal(t, len(testCase.expectUnused), len(report.Unused))
+			if len(testCase.expectUnused) > 0 {
+				require.NoError(t, certdeck.Match(testCase.expectUnused, report.Unused))
+			}
+			require.Equal(t, testCase.expectComplete, report.Complete())
+
+			if !testCase.expectComplete {
+				last := report.Chain[len(report.Chain)-1]
+				require.Equal(t, last.Issuer, report.MissingIssuer.Name)
+				require.Equal(t, last.AuthorityKeyId, report.MissingIssuer.AuthorityKeyID)
+			}
+		})
+	}
+}
+
+func TestOrderChain(t *testing.T) {
+	pool := []*x509.Certificate{testcerts.Chain3Cert, testcerts.Chain2Cert, testcerts.Chain1Cert}
+
+	chain, err := certdeck.OrderChain(testcerts.Chain1Key.Public(), pool)
+	require.NoError(t, err)
+	require.NoError(t, certdeck.Match(
+		[]*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert},
+		chain,
+	))
+
+	// Chain 1 and 2 are not part of the chain of chain 3.
+	_, err = certdeck.OrderChain(testcerts.Chain3Key.Public(), pool)
+	require.ErrorIs(t, err, certdeck.ErrUnusedCertificates)
+
+	_, err = certdeck.OrderChain(testcerts.Chain1Key.Public(), pool[:2])
+	require.ErrorIs(t, err, certdeck.ErrCertKeyMismatch)
+}
@@ -0,0 +1,26 @@
+package certdeck
+
+import "time"
+
+// Clock provides the current time. It can be replaced in tests, to control time-dependent behavior.
+//
+// A controllable implementation is available in the clocktest package.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for the duration to elapse, then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// SystemClock is the default Clock, that reads the system time.
+var SystemClock Clock = systemClock{}
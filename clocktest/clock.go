@@ -0,0 +1,92 @@
+// Package clocktest provides a controllable certdeck.Clock, for testing time-dependent behavior.
+package clocktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/a-novel-kit/certdeck"
+)
+
+type waiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// Clock is a fake certdeck.Clock. Time only moves when Advance or Set is called.
+type Clock struct {
+	now     time.Time
+	waiters []*waiter
+
+	mu sync.Mutex
+}
+
+var _ certdeck.Clock = (*Clock)(nil)
+
+// Now returns the current time of the fake clock.
+func (clock *Clock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	return clock.now
+}
+
+// After returns a channel that receives the time of the fake clock, once it has moved forward by at least d.
+func (clock *Clock) After(d time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- clock.now
+		return ch
+	}
+
+	clock.waiters = append(clock.waiters, &waiter{deadline: clock.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward, and fires every After channel whose deadline has passed.
+func (clock *Clock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	clock.setLocked(clock.now.Add(d))
+}
+
+// Set moves the clock to the given time, and fires every After channel whose deadline has passed.
+func (clock *Clock) Set(now time.Time) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	clock.setLocked(now)
+}
+
+func (clock *Clock) setLocked(now time.Time) {
+	clock.now = now
+
+	pending := clock.waiters[:0]
+	for _, w := range clock.waiters {
+		if now.Before(w.deadline) {
+			pending = append(pending, w)
+			continue
+		}
+
+		w.ch <- now
+	}
+
+	clock.waiters = pending
+}
+
+// Waiters returns the number of After channels that have not fired yet.
+func (clock *Clock) Waiters() int {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+
+	return len(clock.waiters)
+}
+
+// New returns a fake clock, set to the given time.
+func New(now time.Time) *Clock {
+	return &Clock{now: now}
+}
@@ -0,0 +1,35 @@
+package clocktest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck/clocktest"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	clock := clocktest.New(start)
+	require.Equal(t, start, clock.Now())
+
+	immediate := clock.After(0)
+	require.Equal(t, start, <-immediate)
+
+	after := clock.After(time.Minute)
+	require.Equal(t, 1, clock.Waiters())
+
+	clock.Advance(30 * time.Second)
+	require.Equal(t, start.Add(30*time.Second), clock.Now())
+	require.Empty(t, after)
+
+	clock.Advance(30 * time.Second)
+	require.Equal(t, start.Add(time.Minute), <-after)
+	require.Zero(t, clock.Waiters())
+
+	later := clock.After(time.Hour)
+	clock.Set(start.Add(2 * time.Hour))
+	require.Equal(t, start.Add(2*time.Hour), <-later)
+}
@@ -1,16 +1,44 @@
 package certdeck
 
 import (
+	"context"
 	"crypto"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/samber/lo"
+	"golang.org/x/sync/singleflight"
 )
 
 type Collection interface {
 	// Get returns the collection of certificates and private CertKey for the given updater.
 	Get(updater CertsProvider) (CollectionRow, error)
+	// GetContext is like Get, but passes the context to the updater when its data must be fetched. The
+	// deadline and cancellation of the context only apply to this call.
+	GetContext(ctx context.Context, updater CertsProvider) (CollectionRow, error)
+
+	// Register keeps the row of the provider refreshed in the background, until it is unregistered or the
+	// collection is closed. The first refresh happens immediately. Registering an ID twice does nothing.
+	//
+	// Refreshes are retried with an exponential backoff on failure, and happen early when the leaf certificate
+	// is about to expire. Rows of registered providers are never purged.
+	Register(updater CertsProvider) error
+	// Unregister stops refreshing the row of a provider in the background, and waits for the current refresh to
+	// end. The row is then purged normally when it expires.
+	Unregister(id string)
+	// Close stops every background refresh, and waits for them to end. Register fails once the collection is
+	// closed, but cached rows can still be read.
+	Close() error
+
+	// Subscribe calls the callback when the row of a provider changes, or fails to be fetched. A row changes when
+	// Match reports a difference in its chain, or when its key changes. The first fetch of a row is a change.
+	//
+	// Callbacks run in a separate goroutine, one event at a time and in order, so they can use the collection. A
+	// slow callback delays the next events, but not the fetches. The returned function removes the subscription.
+	Subscribe(id string, callback func(event *CollectionEvent)) func()
 }
 
 type CollectionRow interface {
@@ -26,12 +54,26 @@ type CollectionRow interface {
 	KeyPEM() []byte
 }
 
+// TTLHint can be implemented by collection rows or providers, to suggest how long a row should be cached. The
+// hint of the row takes precedence over the one of the provider.
+type TTLHint interface {
+	// TTL returns the suggested cache duration. It returns false if there is no suggestion, in which case the
+	// cache duration of the collection is used. A zero duration with true means the row must not be cached.
+	TTL() (time.Duration, bool)
+}
+
 type CollectionRowBase struct {
 	Certs   []*x509.Certificate
 	CertKey crypto.Signer
 
 	CertsPEM   [][]byte
 	CertKeyPEM []byte
+
+	// MaxAge is the suggested cache duration of the row. If zero, the cache duration of the collection is used.
+	MaxAge time.Duration
+	// NoCache tells the collection not to cache the row, so it is fetched again on every call. The row is never
+	// served stale, even with StaleWhileRevalidate or StaleIfError. It takes precedence over MaxAge.
+	NoCache bool
 }
 
 func (row *CollectionRowBase) Fill() error {
@@ -66,6 +108,14 @@ func (row *CollectionRowBase) KeyPEM() []byte {
 	return row.CertKeyPEM
 }
 
+func (row *CollectionRowBase) TTL() (time.Duration, bool) {
+	if row.NoCache {
+		return 0, true
+	}
+
+	return row.MaxAge, row.MaxAge > 0
+}
+
 // =====================================================================================================================
 // COLLECTION.
 // =====================================================================================================================
@@ -77,67 +127,362 @@ type CertsProvider interface {
 	Retrieve() (CollectionRow, error)
 }
 
+// ContextCertsProvider is a CertsProvider that supports deadlines and cancellation. Collections use
+// RetrieveContext when a provider implements it.
+type ContextCertsProvider interface {
+	CertsProvider
+	// RetrieveContext returns the updated data. It must return early when the context is done.
+	RetrieveContext(ctx context.Context) (CollectionRow, error)
+}
+
+// RetrieveContext returns the updated data of a provider, using its RetrieveContext method if it implements
+// ContextCertsProvider.
+//
+// Otherwise, Retrieve is called in a separate goroutine, and RetrieveContext returns as soon as the context is
+// done. The result of the abandoned call is discarded.
+func RetrieveContext(ctx context.Context, provider CertsProvider) (CollectionRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if contextProvider, ok := provider.(ContextCertsProvider); ok {
+		return contextProvider.RetrieveContext(ctx)
+	}
+
+	type result struct {
+		row CollectionRow
+		err error
+	}
+
+	// Buffered, so the goroutine does not leak when the result is abandoned.
+	done := make(chan result, 1)
+
+	go func() {
+		row, err := provider.Retrieve()
+		done <- result{row: row, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.row, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type certsProviderFunc struct {
+	id       string
+	retrieve func(ctx context.Context) (CollectionRow, error)
+}
+
+func (provider *certsProviderFunc) ID() string {
+	return provider.id
+}
+
+func (provider *certsProviderFunc) Retrieve() (CollectionRow, error) {
+	return provider.retrieve(context.Background())
+}
+
+func (provider *certsProviderFunc) RetrieveContext(ctx context.Context) (CollectionRow, error) {
+	return provider.retrieve(ctx)
+}
+
+// NewCertsProviderFunc returns a ContextCertsProvider from a retrieval function. Its Retrieve method calls the
+// function with context.Background.
+func NewCertsProviderFunc(
+	id string, retrieve func(ctx context.Context) (CollectionRow, error),
+) ContextCertsProvider {
+	return &certsProviderFunc{id: id, retrieve: retrieve}
+}
+
 type collectionImpl struct {
 	cached        map[string]CollectionRow
 	cacheTimes    map[string]time.Time
-	cacheUpdaters map[string]func() (CollectionRow, error)
+	cacheTTLs     map[string]time.Duration
+	cacheUpdaters map[string]CertsProvider
+
+	subscribers map[string][]*subscriber
+	// events holds the events waiting to be delivered to subscribers.
+	events      []*CollectionEvent
+	dispatching bool
+	eventsMu    sync.Mutex
 
-	cacheDuration time.Duration
+	cacheDuration        time.Duration
+	leafValidityFraction float64
+	staleWhileRevalidate time.Duration
+	staleIfError         time.Duration
+	refreshTimeout       time.Duration
+
+	// inFlight deduplicates concurrent fetches of the same row.
+	inFlight singleflight.Group
+
+	refreshInterval     time.Duration
+	refreshJitter       float64
+	refreshBackoff      time.Duration
+	refreshBeforeExpiry time.Duration
+
+	refreshers map[string]*refresher
+	closed     bool
+
+	clock Clock
 
 	sync.RWMutex
 }
 
 func (collection *collectionImpl) Get(updater CertsProvider) (CollectionRow, error) {
+	return collection.GetContext(context.Background(), updater)
+}
+
+func (collection *collectionImpl) GetContext(ctx context.Context, updater CertsProvider) (CollectionRow, error) {
 	name := updater.ID()
 
 	collection.RLock()
-	if row, ok := collection.cached[name]; ok {
-		// Row is cached, data is not refetched.
-		if time.Since(collection.cacheTimes[name]) < collection.cacheDuration {
-			collection.RUnlock()
-			return row, nil
-		}
+	cached, isCached := collection.cached[name]
+	cachedAt := collection.cacheTimes[name]
+	ttl := collection.cacheTTLs[name]
+
+	// Get the registered updater. Whether data is cached or not, it is expired.
+	provider, ok := collection.cacheUpdaters[name]
+	if !ok {
+		provider = updater
 	}
 	collection.RUnlock()
 
-	collection.Lock()
-	defer collection.Unlock()
-	defer collection.purge()
+	age := collection.clock.Now().Sub(cachedAt)
 
-	// Get the update function. Whether data is cached or not, it is expired.
-	updateFn, ok := collection.cacheUpdaters[name]
-	if !ok {
-		collection.cacheUpdaters[name] = updater.Retrieve
-		updateFn = updater.Retrieve
+	if isCached {
+		// Row is cached, data is not refetched.
+		if age < ttl {
+			return cached, nil
+		}
+
+		// Row is stale, it is served while being refreshed in the background. Rows that must not be cached are
+		// never served stale.
+		if ttl > 0 && age < ttl+collection.staleWhileRevalidate {
+			collection.refresh(name, provider)
+			return cached, nil
+		}
 	}
 
-	row, err := updateFn()
+	row, err := collection.fetch(ctx, name, provider)
 	if err != nil {
+		// The context error belongs to the caller, the provider did not fail.
+		if isCached && ttl > 0 && ctx.Err() == nil && age < ttl+collection.staleIfError {
+			return cached, nil
+		}
+
 		return nil, fmt.Errorf("get collection for %s: %w", name, err)
 	}
 
+	return row, nil
+}
+
+// errFetchAbandoned is returned by a shared fetch, when the caller that started it gave up.
+var errFetchAbandoned = errors.New("fetch abandoned by its caller")
+
+// retrieve fetches a row from its provider, and caches it. The lock is not held during the fetch, so a slow
+// provider does not block the other rows.
+func (collection *collectionImpl) retrieve(ctx context.Context, name string, provider CertsProvider) (any, error) {
+	fetchCtx := ctx
+
+	if collection.refreshTimeout > 0 {
+		var cancel context.CancelFunc
+
+		fetchCtx, cancel = context.WithTimeout(ctx, collection.refreshTimeout)
+		defer cancel()
+	}
+
+	row, err := RetrieveContext(fetchCtx, provider)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%w: %w", errFetchAbandoned, err)
+		}
+
+		collection.RLock()
+		old := collection.cached[name]
+		collection.RUnlock()
+
+		collection.notify(&CollectionEvent{ID: name, Old: old, Err: err})
+
+		return nil, err
+	}
+
+	collection.Lock()
+
+	if _, ok := collection.cacheUpdaters[name]; !ok {
+		collection.cacheUpdaters[name] = provider
+	}
+
+	old := collection.cached[name]
+
 	collection.cached[name] = row
-	collection.cacheTimes[name] = time.Now()
+	collection.cacheTimes[name] = collection.clock.Now()
+	collection.cacheTTLs[name] = collection.ttl(row, provider)
+
+	collection.purge()
+	collection.Unlock()
+
+	collection.notify(changeEvent(name, old, row))
+
 	return row, nil
 }
 
-// purge cleans all data that has expired in the cache, to free up memory.
+// fetch waits for the row to be fetched. Concurrent callers share the same fetch, which runs with the context of
+// the first caller. If that caller gives up, the others start a new fetch.
+func (collection *collectionImpl) fetch(
+	ctx context.Context, name string, provider CertsProvider,
+) (CollectionRow, error) {
+	for {
+		result := collection.inFlight.DoChan(name, func() (any, error) {
+			return collection.retrieve(ctx, name, provider)
+		})
+
+		select {
+		case res := <-result:
+			switch {
+			case res.Err == nil:
+				return res.Val.(CollectionRow), nil
+			case ctx.Err() != nil:
+				return nil, ctx.Err()
+			case errors.Is(res.Err, errFetchAbandoned):
+				continue
+			default:
+				return nil, res.Err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// refresh fetches a row in the background, unless a fetch is already running. Errors are ignored, the row is
+// fetched again on the next call.
+func (collection *collectionImpl) refresh(name string, provider CertsProvider) {
+	// The result channel is buffered, so it can be dropped.
+	collection.inFlight.DoChan(name, func() (any, error) {
+		return collection.retrieve(context.Background(), name, provider)
+	})
+}
+
+// ttl returns how long a row is cached. Hints from the row or its provider replace the cache duration of the
+// collection, then the result is capped by the remaining validity of the leaf.
+func (collection *collectionImpl) ttl(row CollectionRow, provider CertsProvider) time.Duration {
+	ttl := collection.cacheDuration
+
+	for _, source := range []any{row, provider} {
+		if hint, ok := source.(TTLHint); ok {
+			if hinted, ok := hint.TTL(); ok {
+				ttl = hinted
+				break
+			}
+		}
+	}
+
+	if certs := row.Certificates(); collection.leafValidityFraction > 0 && len(certs) > 0 {
+		remaining := certs[0].NotAfter.Sub(collection.clock.Now())
+		ttl = min(ttl, max(time.Duration(float64(remaining)*collection.leafValidityFraction), 0))
+	}
+
+	return ttl
+}
+
+// purge cleans all data that has expired in the cache, to free up memory. Stale rows are kept while they can
+// still be served.
 func (collection *collectionImpl) purge() {
+	now := collection.clock.Now()
+	stale := max(collection.staleWhileRevalidate, collection.staleIfError)
+
 	for name, cachedAt := range collection.cacheTimes {
-		if time.Since(cachedAt) > collection.cacheDuration {
+		if _, ok := collection.refreshers[name]; ok {
+			continue
+		}
+
+		if now.Sub(cachedAt) > collection.cacheTTLs[name]+stale {
 			delete(collection.cached, name)
 			delete(collection.cacheTimes, name)
+			delete(collection.cacheTTLs, name)
 			delete(collection.cacheUpdaters, name)
 		}
 	}
 }
 
-func NewCollection(cacheDuration time.Duration) Collection {
+type CollectionConfig struct {
+	// CacheDuration is how long rows are cached, before being fetched again from their provider.
+	CacheDuration time.Duration
+	// LeafValidityFraction caps the cache duration of each row to a fraction of the remaining validity of its
+	// leaf certificate. For example, with 0.5, a leaf that expires in 24 hours is cached for at most 12 hours.
+	//
+	// Disabled by default.
+	LeafValidityFraction float64
+
+	// StaleWhileRevalidate is how long an expired row is still served, while it is fetched again in the
+	// background. Callers only wait for the provider once this window has passed.
+	//
+	// Disabled by default.
+	StaleWhileRevalidate time.Duration
+	// StaleIfError is how long an expired row is still served when fetching it again fails. It avoids failing
+	// TLS handshakes when a provider is briefly unavailable.
+	//
+	// Disabled by default.
+	StaleIfError time.Duration
+	// RefreshTimeout limits the duration of each fetch. Without it, background refreshes can only be stopped by
+	// the provider itself.
+	//
+	// Disabled by default.
+	RefreshTimeout time.Duration
+
+	// RefreshInterval is the delay between background refreshes of registered providers.
+	//
+	// Half of CacheDuration is used by default.
+	RefreshInterval time.Duration
+	// RefreshJitter is the fraction of RefreshInterval that is randomly added or removed from each delay, so
+	// providers are not all refreshed at the same time. Set it to a negative value to disable jitter.
+	//
+	// DefaultRefreshJitter is used by default.
+	RefreshJitter float64
+	// RefreshBackoff is the delay before retrying a failed background refresh. It doubles after each failure, up
+	// to RefreshInterval. It is also the minimum delay between two refreshes.
+	//
+	// DefaultRefreshBackoff is used by default.
+	RefreshBackoff time.Duration
+	// RefreshBeforeExpiry refreshes registered rows early, when their leaf certificate expires within this
+	// duration.
+	//
+	// By default, rows are refreshed when their leaf expires.
+	RefreshBeforeExpiry time.Duration
+
+	// Clock provides the current time.
+	//
+	// SystemClock is used by default.
+	Clock Clock
+}
+
+func NewCollectionWithConfig(config *CollectionConfig) Collection {
 	return &collectionImpl{
 		cached:        make(map[string]CollectionRow),
 		cacheTimes:    make(map[string]time.Time),
-		cacheUpdaters: make(map[string]func() (CollectionRow, error)),
+		cacheTTLs:     make(map[string]time.Duration),
+		cacheUpdaters: make(map[string]CertsProvider),
+
+		subscribers: make(map[string][]*subscriber),
 
-		cacheDuration: cacheDuration,
+		cacheDuration:        config.CacheDuration,
+		leafValidityFraction: config.LeafValidityFraction,
+		staleWhileRevalidate: config.StaleWhileRevalidate,
+		staleIfError:         config.StaleIfError,
+		refreshTimeout:       config.RefreshTimeout,
+
+		refreshInterval:     lo.CoalesceOrEmpty(config.RefreshInterval, config.CacheDuration/2),
+		refreshJitter:       lo.CoalesceOrEmpty(config.RefreshJitter, DefaultRefreshJitter),
+		refreshBackoff:      lo.CoalesceOrEmpty(config.RefreshBackoff, DefaultRefreshBackoff),
+		refreshBeforeExpiry: config.RefreshBeforeExpiry,
+
+		refreshers: make(map[string]*refresher),
+
+		clock: lo.CoalesceOrEmpty(config.Clock, SystemClock),
 	}
 }
+
+func NewCollection(cacheDuration time.Duration) Collection {
+	return NewCollectionWithConfig(&CollectionConfig{CacheDuration: cacheDuration})
+}
@@ -1,13 +1,19 @@
 package certdeck_test
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
 	"crypto/x509"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/a-novel-kit/certdeck"
+	"github.com/a-novel-kit/certdeck/clocktest"
 	"github.com/a-novel-kit/certdeck/internal/certs"
 	certdeckmocks "github.com/a-novel-kit/certdeck/mocks"
 )
@@ -37,7 +43,12 @@ func TestCollection(t *testing.T) {
 	mockUpdater1.On("Retrieve").Return(testRow1, nil).Once()
 	mockUpdater2.On("Retrieve").Return(testRow2, nil).Once()
 
-	collection := certdeck.NewCollection(time.Second)
+	clock := clocktest.New(time.Now())
+
+	collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+		CacheDuration: time.Second,
+		Clock:         clock,
+	})
 
 	data, err := collection.Get(mockUpdater1)
 	require.NoError(t, err)
@@ -55,7 +66,7 @@ func TestCollection(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, testRow1, data)
 
-		time.Sleep(time.Second)
+		clock.Advance(time.Second)
 
 		// Data expired, updater called.
 		data, err = collection.Get(mockUpdater1)
@@ -66,3 +77,490 @@ func TestCollection(t *testing.T) {
 	mockUpdater1.AssertExpectations(t)
 	mockUpdater2.AssertExpectations(t)
 }
+
+func TestCollectionContext(t *testing.T) {
+	testRow1 := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain1Cert},
+		CertKey: certs.Chain1Key,
+	}
+
+	testRow2 := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain2Cert},
+		CertKey: certs.Chain2Key,
+	}
+
+	type ctxKey struct{}
+
+	t.Run("context provider", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "foo")
+
+		mockUpdater := certdeckmocks.NewMockContextCertsProvider(t)
+		mockUpdater.On("ID").Return("test-updater")
+		mockUpdater.On("RetrieveContext", ctx).Return(testRow1, nil).Once()
+
+		collection := certdeck.NewCollection(time.Hour)
+
+		data, err := collection.GetContext(ctx, mockUpdater)
+		require.NoError(t, err)
+		require.Equal(t, testRow1, data)
+
+		// Cached data is returned, even if the context is done.
+		canceledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		data, err = collection.GetContext(canceledCtx, mockUpdater)
+		require.NoError(t, err)
+		require.Equal(t, testRow1, data)
+
+		mockUpdater.AssertExpectations(t)
+	})
+
+	t.Run("slow provider", func(t *testing.T) {
+		release := make(chan struct{})
+		defer close(release)
+
+		slowUpdater := certdeckmocks.NewMockCollectionUpdater(t)
+		slowUpdater.On("ID").Return("slow-updater")
+		slowUpdater.On("Retrieve").Run(func(_ mock.Arguments) { <-release }).Return(testRow1, nil).Once()
+
+		fastUpdater := certdeckmocks.NewMockCollectionUpdater(t)
+		fastUpdater.On("ID").Return("fast-updater")
+		fastUpdater.On("Retrieve").Return(testRow2, nil).Once()
+
+		collection := certdeck.NewCollection(time.Hour)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		slowDone := make(chan error, 1)
+
+		go func() {
+			_, err := collection.GetContext(ctx, slowUpdater)
+			slowDone <- err
+		}()
+
+		// Other rows are not blocked by the slow provider.
+		data, err := collection.Get(fastUpdater)
+		require.NoError(t, err)
+		require.Equal(t, testRow2, data)
+
+		require.ErrorIs(t, <-slowDone, context.DeadlineExceeded)
+	})
+
+	t.Run("provider func", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "foo")
+
+		provider := certdeck.NewCertsProviderFunc("func-updater", func(retrieveCtx context.Context) (certdeck.CollectionRow, error) {
+			if retrieveCtx.Value(ctxKey{}) == nil {
+				return testRow1, nil
+			}
+
+			return testRow2, nil
+		})
+		require.Equal(t, "func-updater", provider.ID())
+
+		data, err := provider.Retrieve()
+		require.NoError(t, err)
+		require.Equal(t, testRow1, data)
+
+		data, err = certdeck.RetrieveContext(ctx, provider)
+		require.NoError(t, err)
+		require.Equal(t, testRow2, data)
+	})
+}
+
+func TestCollectionRefresh(t *testing.T) {
+	testRow1 := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain1Cert},
+		CertKey: certs.Chain1Key,
+	}
+
+	testRow2 := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain2Cert},
+		CertKey: certs.Chain2Key,
+	}
+
+	t.Run("deduplicate fetches", func(t *testing.T) {
+		release := make(chan struct{})
+		started := make(chan struct{})
+
+		mockUpdater := certdeckmocks.NewMockCollectionUpdater(t)
+		mockUpdater.On("ID").Return("test-updater")
+		mockUpdater.On("Retrieve").
+			Run(func(_ mock.Arguments) {
+				close(started)
+				<-release
+			}).
+			Return(testRow1, nil).
+			Once()
+
+		collection := certdeck.NewCollection(time.Hour)
+
+		type result struct {
+			data certdeck.CollectionRow
+			err  error
+		}
+
+		results := make(chan result, 5)
+
+		for range 5 {
+			go func() {
+				data, err := collection.Get(mockUpdater)
+				results <- result{data: data, err: err}
+			}()
+		}
+
+		<-started
+		// Let the other callers join the running fetch.
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+
+		for range 5 {
+			res := <-results
+			require.NoError(t, res.err)
+			require.Equal(t, testRow1, res.data)
+		}
+
+		mockUpdater.AssertExpectations(t)
+	})
+
+	t.Run("abandoned fetch", func(t *testing.T) {
+		release := make(chan struct{})
+		started := make(chan struct{})
+
+		calls := 0
+		retrieve := func(ctx context.Context) (certdeck.CollectionRow, error) {
+			calls++
+			if calls > 1 {
+				return testRow2, nil
+			}
+
+			close(started)
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-release:
+				return testRow1, nil
+			}
+		}
+
+		provider := certdeck.NewCertsProviderFunc("test-updater", retrieve)
+
+		collection := certdeck.NewCollection(time.Hour)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		firstDone := make(chan error, 1)
+
+		go func() {
+			_, err := collection.GetContext(ctx, provider)
+			firstDone <- err
+		}()
+
+		<-started
+
+		secondDone := make(chan certdeck.CollectionRow, 1)
+
+		go func() {
+			// Errors are reported as a nil row.
+			data, _ := collection.Get(provider)
+			secondDone <- data
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+
+		// The first caller gave up, the second one fetches the row again.
+		require.ErrorIs(t, <-firstDone, context.Canceled)
+		require.Equal(t, testRow2, <-secondDone)
+		close(release)
+	})
+
+	t.Run("stale while revalidate", func(t *testing.T) {
+		refreshed := make(chan struct{})
+
+		mockUpdater := certdeckmocks.NewMockCollectionUpdater(t)
+		mockUpdater.On("ID").Return("test-updater")
+		mockUpdater.On("Retrieve").Return(testRow1, nil).Once()
+
+		clock := clocktest.New(time.Now())
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration:        time.Minute,
+			StaleWhileRevalidate: time.Minute,
+			Clock:                clock,
+		})
+
+		data, err := collection.Get(mockUpdater)
+		require.NoError(t, err)
+		require.Equal(t, testRow1, data)
+
+		mockUpdater.On("Retrieve").Run(func(_ mock.Arguments) { <-refreshed }).Return(testRow2, nil).Once()
+
+		// The stale row is served, while the new one is fetched.
+		clock.Advance(90 * time.Second)
+
+		data, err = collection.Get(mockUpdater)
+		require.NoError(t, err)
+		require.Equal(t, testRow1, data)
+
+		data, err = collection.Get(mockUpdater)
+		require.NoError(t, err)
+		require.Equal(t, testRow1, data)
+
+		close(refreshed)
+
+		require.Eventually(t, func() bool {
+			data, err = collection.Get(mockUpdater)
+			return err == nil && data == testRow2
+		}, time.Second, 10*time.Millisecond)
+
+		mockUpdater.AssertExpectations(t)
+	})
+
+	t.Run("stale if error", func(t *testing.T) {
+		errFoo := errors.New("foo")
+
+		mockUpdater := certdeckmocks.NewMockCollectionUpdater(t)
+		mockUpdater.On("ID").Return("test-updater")
+		mockUpdater.On("Retrieve").Return(testRow1, nil).Once()
+
+		clock := clocktest.New(time.Now())
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration: time.Minute,
+			StaleIfError:  time.Minute,
+			Clock:         clock,
+		})
+
+		data, err := collection.Get(mockUpdater)
+		require.NoError(t, err)
+		require.Equal(t, testRow1, data)
+
+		mockUpdater.On("Retrieve").Return(nil, errFoo).Twice()
+
+		// Fetch fails, the stale row is served.
+		clock.Advance(90 * time.Second)
+
+		data, err = collection.Get(mockUpdater)
+		require.NoError(t, err)
+		require.Equal(t, testRow1, data)
+
+		// Stale window has passed.
+		clock.Advance(time.Minute)
+
+		_, err = collection.Get(mockUpdater)
+		require.ErrorIs(t, err, errFoo)
+
+		mockUpdater.AssertExpectations(t)
+	})
+}
+
+type ttlHintProvider struct {
+	certdeck.CertsProvider
+
+	ttl time.Duration
+}
+
+func (provider *ttlHintProvider) TTL() (time.Duration, bool) {
+	return provider.ttl, true
+}
+
+func TestCollectionTTL(t *testing.T) {
+	// Far from the expiration of the fixtures.
+	start := certs.Chain1Cert.NotBefore.Add(time.Hour)
+
+	t.Run("row hint", func(t *testing.T) {
+		testRow := &certdeck.CollectionRowBase{
+			Certs:   []*x509.Certificate{certs.Chain1Cert},
+			CertKey: certs.Chain1Key,
+			MaxAge:  time.Minute,
+		}
+
+		mockUpdater := certdeckmocks.NewMockCollectionUpdater(t)
+		mockUpdater.On("ID").Return("test-updater")
+		mockUpdater.On("Retrieve").Return(testRow, nil).Once()
+
+		clock := clocktest.New(start)
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration: time.Hour,
+			Clock:         clock,
+		})
+
+		_, err := collection.Get(mockUpdater)
+		require.NoError(t, err)
+
+		clock.Advance(59 * time.Second)
+
+		_, err = collection.Get(mockUpdater)
+		require.NoError(t, err)
+
+		// The hint of the row replaces the cache duration.
+		mockUpdater.On("Retrieve").Return(testRow, nil).Once()
+		clock.Advance(time.Second)
+
+		_, err = collection.Get(mockUpdater)
+		require.NoError(t, err)
+
+		mockUpdater.AssertExpectations(t)
+	})
+
+	t.Run("no cache", func(t *testing.T) {
+		testRow := &certdeck.CollectionRowBase{
+			Certs:   []*x509.Certificate{certs.Chain1Cert},
+			CertKey: certs.Chain1Key,
+			MaxAge:  time.Minute,
+			NoCache: true,
+		}
+
+		mockUpdater := certdeckmocks.NewMockCollectionUpdater(t)
+		mockUpdater.On("ID").Return("test-updater")
+		mockUpdater.On("Retrieve").Return(testRow, nil).Twice()
+
+		provider := &ttlHintProvider{CertsProvider: mockUpdater, ttl: time.Hour}
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration: time.Hour,
+			Clock:         clocktest.New(start),
+		})
+
+		// The row is fetched on every call, whatever the hint of the provider.
+		_, err := collection.Get(provider)
+		require.NoError(t, err)
+
+		_, err = collection.Get(provider)
+		require.NoError(t, err)
+
+		mockUpdater.AssertExpectations(t)
+	})
+
+	t.Run("no cache is never stale", func(t *testing.T) {
+		errFoo := errors.New("foo")
+
+		testRow := &certdeck.CollectionRowBase{
+			Certs:   []*x509.Certificate{certs.Chain1Cert},
+			CertKey: certs.Chain1Key,
+			NoCache: true,
+		}
+
+		mockUpdater := certdeckmocks.NewMockCollectionUpdater(t)
+		mockUpdater.On("ID").Return("test-updater")
+		mockUpdater.On("Retrieve").Return(testRow, nil).Twice()
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration:        time.Hour,
+			StaleWhileRevalidate: time.Hour,
+			StaleIfError:         time.Hour,
+			Clock:                clocktest.New(start),
+		})
+
+		_, err := collection.Get(mockUpdater)
+		require.NoError(t, err)
+
+		// The row is fetched again, instead of being served while revalidating.
+		_, err = collection.Get(mockUpdater)
+		require.NoError(t, err)
+
+		// Errors are returned, instead of the cached row.
+		mockUpdater.On("Retrieve").Return(nil, errFoo).Once()
+
+		_, err = collection.Get(mockUpdater)
+		require.ErrorIs(t, err, errFoo)
+
+		mockUpdater.AssertExpectations(t)
+	})
+
+	t.Run("provider hint", func(t *testing.T) {
+		testRow := &certdeck.CollectionRowBase{
+			Certs:   []*x509.Certificate{certs.Chain1Cert},
+			CertKey: certs.Chain1Key,
+		}
+
+		mockUpdater := certdeckmocks.NewMockCollectionUpdater(t)
+		mockUpdater.On("ID").Return("test-updater")
+		mockUpdater.On("Retrieve").Return(testRow, nil).Twice()
+
+		provider := &ttlHintProvider{CertsProvider: mockUpdater, ttl: 2 * time.Hour}
+
+		clock := clocktest.New(start)
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration: time.Hour,
+			Clock:         clock,
+		})
+
+		_, err := collection.Get(provider)
+		require.NoError(t, err)
+
+		// The row has no hint, so the one of the provider is used.
+		clock.Advance(2*time.Hour - time.Second)
+
+		_, err = collection.Get(provider)
+		require.NoError(t, err)
+
+		clock.Advance(time.Second)
+
+		_, err = collection.Get(provider)
+		require.NoError(t, err)
+
+		mockUpdater.AssertExpectations(t)
+	})
+
+	t.Run("leaf validity fraction", func(t *testing.T) {
+		testRow := &certdeck.CollectionRowBase{
+			Certs:   []*x509.Certificate{certs.Chain1Cert},
+			CertKey: certs.Chain1Key,
+			MaxAge:  24 * time.Hour,
+		}
+
+		mockUpdater := certdeckmocks.NewMockCollectionUpdater(t)
+		mockUpdater.On("ID").Return("test-updater")
+		mockUpdater.On("Retrieve").Return(testRow, nil).Once()
+
+		// The leaf expires in 4 hours.
+		clock := clocktest.New(certs.Chain1Cert.NotAfter.Add(-4 * time.Hour))
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration:        time.Hour,
+			LeafValidityFraction: 0.5,
+			Clock:                clock,
+		})
+
+		_, err := collection.Get(mockUpdater)
+		require.NoError(t, err)
+
+		// The hint is capped at half the remaining validity.
+		clock.Advance(2*time.Hour - time.Second)
+
+		_, err = collection.Get(mockUpdater)
+		require.NoError(t, err)
+
+		mockUpdater.On("Retrieve").Return(testRow, nil).Once()
+		clock.Advance(time.Second)
+
+		_, err = collection.Get(mockUpdater)
+		require.NoError(t, err)
+
+		mockUpdater.AssertExpectations(t)
+	})
+}
+
+func TestCollectionRowBaseFill(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	row := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain1Cert},
+		CertKey: key,
+	}
+	require.NoError(t, row.Fill())
+
+	decoded, err := certdeck.PEMToKey(row.KeyPEM())
+	require.NoError(t, err)
+	require.True(t, key.Equal(decoded))
+
+	decodedCerts, err := certdeck.PEMToCerts(row.CertificatesPEM())
+	require.NoError(t, err)
+	require.NoError(t, certdeck.Match(row.Certificates(), decodedCerts))
+}
@@ -0,0 +1,194 @@
+package certdeck
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var ErrNameConstraint = errors.New("name not allowed by the issuer constraints")
+
+// NameConstraints restricts the names a certificate authority can issue certificates for, as defined in
+// RFC 5280, section 4.2.1.10.
+//
+// Domain constraints match the domain itself and all its subdomains. A constraint starting with a dot, like
+// ".example.com", only matches subdomains. Email constraints can be a full mailbox ("admin@example.com"), a
+// host ("example.com"), or a domain (".example.com"). URI constraints apply to the host of the URI.
+type NameConstraints struct {
+	PermittedDNSDomains []string
+	ExcludedDNSDomains  []string
+
+	PermittedIPRanges []*net.IPNet
+	ExcludedIPRanges  []*net.IPNet
+
+	PermittedEmailAddresses []string
+	ExcludedEmailAddresses  []string
+
+	PermittedURIDomains []string
+	ExcludedURIDomains  []string
+
+	// Critical marks the name constraints extension as critical.
+	Critical bool
+}
+
+func (constraints *NameConstraints) isEmpty() bool {
+	return len(constraints.PermittedDNSDomains) == 0 && len(constraints.ExcludedDNSDomains) == 0 &&
+		len(constraints.PermittedIPRanges) == 0 && len(constraints.ExcludedIPRanges) == 0 &&
+		len(constraints.PermittedEmailAddresses) == 0 && len(constraints.ExcludedEmailAddresses) == 0 &&
+		len(constraints.PermittedURIDomains) == 0 && len(constraints.ExcludedURIDomains) == 0
+}
+
+// applyNameConstraints copies the name constraints of the template to the certificate.
+func applyNameConstraints(x509Template *x509.Certificate, constraints *NameConstraints, isCA bool) error {
+	if constraints == nil || constraints.isEmpty() {
+		return nil
+	}
+
+	if !isCA {
+		return fmt.Errorf("%w: name constraints only apply to certificate authorities", ErrInvalidTemplate)
+	}
+
+	x509Template.PermittedDNSDomains = constraints.PermittedDNSDomains
+	x509Template.ExcludedDNSDomains = constraints.ExcludedDNSDomains
+	x509Template.PermittedIPRanges = constraints.PermittedIPRanges
+	x509Template.ExcludedIPRanges = constraints.ExcludedIPRanges
+	x509Template.PermittedEmailAddresses = constraints.PermittedEmailAddresses
+	x509Template.ExcludedEmailAddresses = constraints.ExcludedEmailAddresses
+	x509Template.PermittedURIDomains = constraints.PermittedURIDomains
+	x509Template.ExcludedURIDomains = constraints.ExcludedURIDomains
+	x509Template.PermittedDNSDomainsCritical = constraints.Critical
+
+	return nil
+}
+
+func matchDomainConstraint(domain, constraint string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	constraint = strings.ToLower(constraint)
+
+	if constraint == "" {
+		return true
+	}
+
+	// Leading dot: subdomains only.
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(domain, constraint)
+	}
+
+	return domain == constraint || strings.HasSuffix(domain, "."+constraint)
+}
+
+func matchEmailConstraint(email, constraint string) bool {
+	// Full mailbox.
+	if strings.Contains(constraint, "@") {
+		return strings.EqualFold(email, constraint)
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	host := strings.ToLower(email[at+1:])
+	constraint = strings.ToLower(constraint)
+
+	// Leading dot: any host in the domain, excluding the domain itself.
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(host, constraint)
+	}
+
+	return host == constraint
+}
+
+func matchURIConstraint(uri *url.URL, constraint string) bool {
+	host := strings.ToLower(uri.Hostname())
+	constraint = strings.ToLower(constraint)
+
+	if strings.HasPrefix(constraint, ".") {
+		return strings.HasSuffix(host, constraint)
+	}
+
+	return host == constraint
+}
+
+func matchIPConstraint(ip net.IP, constraint *net.IPNet) bool {
+	// Do not match IPv4 addresses against IPv6 ranges, and conversely.
+	if (ip.To4() == nil) != (constraint.IP.To4() == nil) {
+		return false
+	}
+
+	return constraint.Contains(ip)
+}
+
+// checkName checks a single name against the permitted and excluded constraints of a certificate authority.
+func checkName[N any, C any](
+	kind, name string, value N, permitted, excluded []C, match func(N, C) bool,
+) error {
+	for _, constraint := range excluded {
+		if match(value, constraint) {
+			return fmt.Errorf("%w: %s %s is excluded", ErrNameConstraint, kind, name)
+		}
+	}
+
+	if len(permitted) == 0 {
+		return nil
+	}
+
+	for _, constraint := range permitted {
+		if match(value, constraint) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s %s is not permitted", ErrNameConstraint, kind, name)
+}
+
+// checkNameConstraints makes sure the names of a certificate are allowed by the constraints of every
+// certificate authority in the issuer chain.
+func checkNameConstraints(issuers []*x509.Certificate, cert *x509.Certificate) error {
+	for _, issuer := range issuers {
+		for _, dnsName := range cert.DNSNames {
+			err := checkName(
+				"DNS name", dnsName, dnsName,
+				issuer.PermittedDNSDomains, issuer.ExcludedDNSDomains, matchDomainConstraint,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, ip := range cert.IPAddresses {
+			err := checkName(
+				"IP address", ip.String(), ip,
+				issuer.PermittedIPRanges, issuer.ExcludedIPRanges, matchIPConstraint,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, email := range cert.EmailAddresses {
+			err := checkName(
+				"email address", email, email,
+				issuer.PermittedEmailAddresses, issuer.ExcludedEmailAddresses, matchEmailConstraint,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, uri := range cert.URIs {
+			err := checkName(
+				"URI", uri.String(), uri,
+				issuer.PermittedURIDomains, issuer.ExcludedURIDomains, matchURIConstraint,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package certdeck_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	certdeckmocks "github.com/a-novel-kit/certdeck/mocks"
+)
+
+func TestSignerNameConstraints(t *testing.T) {
+	store := certdeckmocks.NewMockSerialStore(t)
+	store.On("Insert", context.Background(), mock.Anything).Return(nil)
+
+	newKey := func(t *testing.T) *ecdsa.PrivateKey {
+		t.Helper()
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		return key
+	}
+
+	_, tenantRange, err := net.ParseCIDR("10.1.0.0/16")
+	require.NoError(t, err)
+	_, excludedRange, err := net.ParseCIDR("10.1.2.0/24")
+	require.NoError(t, err)
+
+	rootKey := newKey(t)
+	rootCert, err := certdeck.NewSigner(&certdeck.SignerConfig{SerialStore: store}).Sign(
+		context.Background(), rootKey, nil,
+		&certdeck.Template{Exp: time.Hour},
+	)
+	require.NoError(t, err)
+
+	rootSigner := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore: store,
+		IssuerChain: []*x509.Certificate{rootCert},
+		IssuerKey:   rootKey,
+	})
+
+	tenantKey := newKey(t)
+	tenantCert, err := rootSigner.Sign(
+		context.Background(), tenantKey.Public(), nil,
+		&certdeck.Template{
+			Exp: time.Hour,
+			NameConstraints: &certdeck.NameConstraints{
+				PermittedDNSDomains:     []string{"tenant.example.com"},
+				ExcludedDNSDomains:      []string{"admin.tenant.example.com"},
+				PermittedIPRanges:       []*net.IPNet{tenantRange},
+				ExcludedIPRanges:        []*net.IPNet{excludedRange},
+				PermittedEmailAddresses: []string{"tenant.example.com"},
+				PermittedURIDomains:     []string{".tenant.example.com"},
+				Critical:                true,
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"tenant.example.com"}, tenantCert.PermittedDNSDomains)
+	require.True(t, tenantCert.PermittedDNSDomainsCritical)
+
+	tenantSigner := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore: store,
+		IssuerChain: []*x509.Certificate{tenantCert, rootCert},
+		IssuerKey:   tenantKey,
+	})
+
+	testCases := []struct {
+		name string
+
+		template *certdeck.Template
+
+		expect error
+	}{
+		{
+			name: "permitted",
+
+			template: &certdeck.Template{
+				DNSNames:    []string{"tenant.example.com", "api.tenant.example.com"},
+				IPAddresses: []net.IP{net.ParseIP("10.1.0.1")},
+				LeafOnly:    true,
+			},
+		},
+		{
+			name: "email and URI permitted",
+
+			template: &certdeck.Template{
+				EmailAddresses: []string{"admin@tenant.example.com"},
+				URIs:           []*url.URL{{Scheme: "https", Host: "api.tenant.example.com"}},
+				LeafOnly:       true,
+			},
+		},
+		{
+			name: "email not permitted",
+
+			template: &certdeck.Template{
+				EmailAddresses: []string{"admin@other.example.com"},
+				LeafOnly:       true,
+			},
+
+			expect: certdeck.ErrNameConstraint,
+		},
+		{
+			name: "URI not permitted",
+
+			template: &certdeck.Template{
+				URIs:     []*url.URL{{Scheme: "https", Host: "tenant.example.com"}},
+				LeafOnly: true,
+			},
+
+			expect: certdeck.ErrNameConstraint,
+		},
+		{
+			name: "DNS name not permitted",
+
+			template: &certdeck.Template{
+				DNSNames: []string{"other.example.com"},
+				LeafOnly: true,
+			},
+
+			expect: certdeck.ErrNameConstraint,
+		},
+		{
+			name: "DNS name excluded",
+
+			template: &certdeck.Template{
+				DNSNames: []string{"api.admin.tenant.example.com"},
+				LeafOnly: true,
+			},
+
+			expect: certdeck.ErrNameConstraint,
+		},
+		{
+			name: "IP address not permitted",
+
+			template: &certdeck.Template{
+				IPAddresses: []net.IP{net.ParseIP("10.2.0.1")},
+				LeafOnly:    true,
+			},
+
+			expect: certdeck.ErrNameConstraint,
+		},
+		{
+			name: "IP address excluded",
+
+			template: &certdeck.Template{
+				IPAddresses: []net.IP{net.ParseIP("10.1.2.3")},
+				LeafOnly:    true,
+			},
+
+			expect: certdeck.ErrNameConstraint,
+		},
+		{
+			name: "IPv6 address not permitted",
+
+			template: &certdeck.Template{
+				IPAddresses: []net.IP{net.IPv6loopback},
+				LeafOnly:    true,
+			},
+
+			expect: certdeck.ErrNameConstraint,
+		},
+		{
+			name: "constraints on leaf",
+
+			template: &certdeck.Template{
+				DNSNames: []string{"tenant.example.com"},
+				LeafOnly: true,
+				NameConstraints: &certdeck.NameConstraints{
+					PermittedDNSDomains: []string{"tenant.example.com"},
+				},
+			},
+
+			expect: certdeck.ErrInvalidTemplate,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			key := newKey(t)
+
+			testCase.template.Exp = time.Minute
+
+			cert, err := tenantSigner.Sign(
+				context.Background(), key.Public(), nil, testCase.template,
+			)
+			require.ErrorIs(t, err, testCase.expect)
+
+			if testCase.expect != nil {
+				return
+			}
+
+			roots := x509.NewCertPool()
+			roots.AddCert(rootCert)
+
+			intermediates := x509.NewCertPool()
+			intermediates.AddCert(tenantCert)
+
+			_, err = cert.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+			require.NoError(t, err)
+		})
+	}
+}
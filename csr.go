@@ -0,0 +1,165 @@
+package certdeck
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+var (
+	ErrInvalidCSR  = errors.New("invalid certificate signing request")
+	ErrCSRRejected = errors.New("certificate signing request rejected by policy")
+)
+
+// CSRFieldAction tells a CSRPolicy what to do with a value requested in a certificate signing request.
+type CSRFieldAction int
+
+const (
+	// CSRFieldKeep uses the value requested in the CSR. If the CSR does not request a value, the one from the
+	// policy template is used instead.
+	CSRFieldKeep CSRFieldAction = iota
+	// CSRFieldOverride ignores the value requested in the CSR, and always uses the one from the policy template.
+	CSRFieldOverride
+	// CSRFieldReject rejects the CSR if it requests a value for this field. The value from the policy template is
+	// used otherwise.
+	CSRFieldReject
+)
+
+// CSRPolicy decides which fields requested in a certificate signing request end up in the issued certificate.
+//
+// The public key of the issued certificate is always the one from the CSR.
+type CSRPolicy struct {
+	// Template provides the base values of the issued certificate. Fields that are not covered by the CSR, like
+	// Exp, are always taken from here.
+	//
+	// If nil, an empty template is used.
+	Template *Template
+	// AllowCA lets the template issue a certificate authority, when its LeafOnly field is false. Otherwise,
+	// certificates issued from a CSR are always leaves.
+	AllowCA bool
+
+	// Subject tells what to do with the subject requested in the CSR.
+	Subject CSRFieldAction
+	// DNSNames tells what to do with the DNS names requested in the CSR.
+	DNSNames CSRFieldAction
+	// IPAddresses tells what to do with the IP addresses requested in the CSR.
+	IPAddresses CSRFieldAction
+	// URIs tells what to do with the URIs requested in the CSR.
+	URIs CSRFieldAction
+	// EmailAddresses tells what to do with the email addresses requested in the CSR.
+	EmailAddresses CSRFieldAction
+
+	// Validate is an optional hook, called with the CSR and the resulting template before the certificate is
+	// signed. Returning an error rejects the CSR.
+	Validate func(csr *x509.CertificateRequest, template *Template) error
+}
+
+func applyCSRField[T any](name string, action CSRFieldAction, requested, base T, isEmpty func(T) bool) (T, error) {
+	switch action {
+	case CSRFieldKeep:
+		if isEmpty(requested) {
+			return base, nil
+		}
+
+		return requested, nil
+	case CSRFieldOverride:
+		return base, nil
+	case CSRFieldReject:
+		if !isEmpty(requested) {
+			return base, fmt.Errorf("%w: %s is not allowed", ErrCSRRejected, name)
+		}
+
+		return base, nil
+	default:
+		return base, fmt.Errorf("unknown action %d for %s", action, name)
+	}
+}
+
+// Apply returns the template used to sign the given CSR. The CSR signature is not checked.
+func (policy *CSRPolicy) Apply(csr *x509.CertificateRequest) (*Template, error) {
+	var template Template
+	if policy.Template != nil {
+		template = *policy.Template
+	}
+
+	// Anyone able to send a CSR must not get a certificate authority by default.
+	template.LeafOnly = template.LeafOnly || !policy.AllowCA
+
+	var err error
+
+	template.Name, err = applyCSRField(
+		"subject", policy.Subject, csr.Subject, template.Name,
+		func(name pkix.Name) bool { return len(name.ToRDNSequence()) == 0 },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	template.DNSNames, err = applyCSRField(
+		"DNS names", policy.DNSNames, csr.DNSNames, template.DNSNames,
+		func(names []string) bool { return len(names) == 0 },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	template.IPAddresses, err = applyCSRField(
+		"IP addresses", policy.IPAddresses, csr.IPAddresses, template.IPAddresses,
+		func(ips []net.IP) bool { return len(ips) == 0 },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	template.URIs, err = applyCSRField(
+		"URIs", policy.URIs, csr.URIs, template.URIs,
+		func(uris []*url.URL) bool { return len(uris) == 0 },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	template.EmailAddresses, err = applyCSRField(
+		"email addresses", policy.EmailAddresses, csr.EmailAddresses, template.EmailAddresses,
+		func(emails []string) bool { return len(emails) == 0 },
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy.Validate != nil {
+		if err = policy.Validate(csr, &template); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCSRRejected, err)
+		}
+	}
+
+	return &template, nil
+}
+
+func (signer *signerImpl) SignCSR(
+	ctx context.Context, csr *x509.CertificateRequest, policy *CSRPolicy,
+) (*x509.Certificate, error) {
+	if csr == nil {
+		return nil, fmt.Errorf("%w: nil request", ErrInvalidCSR)
+	}
+
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCSR, err)
+	}
+
+	if policy == nil {
+		policy = &CSRPolicy{}
+	}
+
+	template, err := policy.Apply(csr)
+	if err != nil {
+		return nil, fmt.Errorf("apply policy: %w", err)
+	}
+
+	// The key ID is derived from the public key of the CSR.
+	return signer.Sign(ctx, csr.PublicKey, nil, template)
+}
@@ -88,6 +88,30 @@ func PEMInlineToCerts(data []byte) ([]*x509.Certificate, error) {
 	return output, nil
 }
 
+// PKCS7ToCerts decodes the certificates of a DER PKCS#7 bundle (.p7b). Signatures, if any, are not checked.
+func PKCS7ToCerts(data []byte) ([]*x509.Certificate, error) {
+	certs, _, err := parsePKCS7(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return certs, nil
+}
+
+// PKCS7PEMToCerts decodes the certificates of a PEM PKCS#7 bundle (.p7b). Signatures, if any, are not checked.
+func PKCS7PEMToCerts(data []byte) ([]*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("decode pem block: no block found")
+	}
+
+	if block.Type != pemTypePKCS7 {
+		return nil, fmt.Errorf("%w: unexpected PEM block type %s", ErrInvalidPKCS7, block.Type)
+	}
+
+	return PKCS7ToCerts(block.Bytes)
+}
+
 func Base64ToCerts(data []string) ([]*x509.Certificate, error) {
 	out := make([]*x509.Certificate, len(data))
 
@@ -108,6 +132,60 @@ func Base64ToCerts(data []string) ([]*x509.Certificate, error) {
 	return out, nil
 }
 
+func DERToCSR(data []byte) (*x509.CertificateRequest, error) {
+	csr, err := x509.ParseCertificateRequest(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate request: %w", err)
+	}
+
+	return csr, nil
+}
+
+func PEMToCSR(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("decode pem block: no block found")
+	}
+
+	// Some older tools use the "NEW CERTIFICATE REQUEST" type.
+	if block.Type != pemTypeCSR && block.Type != pemTypeCSRLegacy {
+		return nil, fmt.Errorf("parse certificate request: unexpected PEM block type %s", block.Type)
+	}
+
+	return DERToCSR(block.Bytes)
+}
+
+func PEMOrDERToCSR(data []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(data)
+	if block != nil {
+		data = block.Bytes
+	}
+
+	return DERToCSR(data)
+}
+
+func DERToCRL(data []byte) (*x509.RevocationList, error) {
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse revocation list: %w", err)
+	}
+
+	return crl, nil
+}
+
+func PEMToCRL(data []byte) (*x509.RevocationList, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("decode pem block: no block found")
+	}
+
+	if block.Type != pemTypeCRL {
+		return nil, fmt.Errorf("parse revocation list: unexpected PEM block type %s", block.Type)
+	}
+
+	return DERToCRL(block.Bytes)
+}
+
 func DERToKey(data []byte) (crypto.Signer, error) {
 	if key, err := x509.ParsePKCS1PrivateKey(data); err == nil {
 		return key, nil
@@ -135,30 +213,52 @@ func PEMToKey(data []byte) (crypto.Signer, error) {
 		return nil, errors.New("decode pem block: no block found")
 	}
 
-	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
-		return key, nil
+	return pemBlockToKey(block)
+}
+
+// pemBlockToKey parses a private key, using the parser that matches the type of the PEM block.
+func pemBlockToKey(block *pem.Block) (crypto.Signer, error) {
+	//nolint:staticcheck // Only used to report legacy encrypted blocks.
+	if block.Type == pemTypeEncryptedPKCS8 || x509.IsEncryptedPEMBlock(block) {
+		return nil, fmt.Errorf("%w: use EncryptedPEMToKey", ErrEncryptedKey)
 	}
 
-	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+	switch block.Type {
+	case pemTypePKCS1:
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse PKCS#1 private key: %w", err)
+		}
+
 		return key, nil
-	}
+	case pemTypeSEC1:
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse SEC1 private key: %w", err)
+		}
+
+		return key, nil
+	case pemTypePKCS8:
+		rawKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse PKCS#8 private key: %w", err)
+		}
 
-	if rawKey, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
 		key, ok := rawKey.(crypto.Signer)
 		if !ok {
 			return nil, ErrUnsupportedKeyFormat
 		}
 
 		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: unexpected PEM block type %s", ErrUnsupportedKeyFormat, block.Type)
 	}
-
-	return nil, ErrUnsupportedKeyFormat
 }
 
 func PEMOrDerToKey(data []byte) (crypto.Signer, error) {
 	block, _ := pem.Decode(data)
 	if block != nil {
-		data = block.Bytes
+		return pemBlockToKey(block)
 	}
 
 	return DERToKey(data)
@@ -0,0 +1,190 @@
+package certdeck
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CertDiffKind tells how a position of a chain changed.
+type CertDiffKind string
+
+const (
+	// CertDiffAdded is a certificate that only exists in the new chain.
+	CertDiffAdded CertDiffKind = "added"
+	// CertDiffRemoved is a certificate that only exists in the old chain.
+	CertDiffRemoved CertDiffKind = "removed"
+	// CertDiffChanged is a certificate that was replaced by a different one.
+	CertDiffChanged CertDiffKind = "changed"
+)
+
+// CertSummary holds the fields of a certificate compared by DiffChains.
+type CertSummary struct {
+	Serial    string    `json:"serial"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	SANs      []string  `json:"sans,omitempty"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	// KeyFingerprint is the SHA-256 fingerprint of the public key.
+	KeyFingerprint string `json:"keyFingerprint"`
+	// Fingerprint is the SHA-256 fingerprint of the DER encoded certificate.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// FieldChange is a field of a certificate that changed between two chains.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// CertDiff is the difference between two chains, at a given position.
+type CertDiff struct {
+	// Position of the certificate in the chains, 0 being the leaf.
+	Position int          `json:"position"`
+	Kind     CertDiffKind `json:"kind"`
+	// Old is the certificate of the first chain. It is nil for added certificates.
+	Old *CertSummary `json:"old,omitempty"`
+	// New is the certificate of the second chain. It is nil for removed certificates.
+	New *CertSummary `json:"new,omitempty"`
+	// Changes lists the fields that differ, for changed certificates. A "certificate" change is reported when
+	// all the compared fields are equal, but the certificates are not.
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// ChainDiff is the result of DiffChains. It can be printed as text with String, or encoded as JSON.
+type ChainDiff struct {
+	Diffs []*CertDiff `json:"diffs"`
+}
+
+// Empty reports whether both chains are equal.
+func (diff *ChainDiff) Empty() bool {
+	return len(diff.Diffs) == 0
+}
+
+func summaryLabel(summary *CertSummary) string {
+	return fmt.Sprintf("%s (serial %s)", summary.Subject, summary.Serial)
+}
+
+// String returns a readable description of the differences, with one line per added or removed certificate, and
+// one line per changed field.
+func (diff *ChainDiff) String() string {
+	if diff.Empty() {
+		return "chains are equal"
+	}
+
+	var builder strings.Builder
+
+	for pos, certDiff := range diff.Diffs {
+		if pos > 0 {
+			builder.WriteString("\n")
+		}
+
+		switch certDiff.Kind {
+		case CertDiffAdded:
+			fmt.Fprintf(&builder, "certificate %d added: %s", certDiff.Position, summaryLabel(certDiff.New))
+		case CertDiffRemoved:
+			fmt.Fprintf(&builder, "certificate %d removed: %s", certDiff.Position, summaryLabel(certDiff.Old))
+		case CertDiffChanged:
+			fmt.Fprintf(&builder, "certificate %d changed: %s", certDiff.Position, summaryLabel(certDiff.Old))
+
+			for _, change := range certDiff.Changes {
+				fmt.Fprintf(&builder, "\n\t%s: %s -> %s", change.Field, change.Old, change.New)
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+func subjectAltNames(cert *x509.Certificate) []string {
+	var names []string
+
+	for _, dnsName := range cert.DNSNames {
+		names = append(names, "DNS:"+dnsName)
+	}
+
+	for _, ip := range cert.IPAddresses {
+		names = append(names, "IP:"+ip.String())
+	}
+
+	for _, email := range cert.EmailAddresses {
+		names = append(names, "email:"+email)
+	}
+
+	for _, uri := range cert.URIs {
+		names = append(names, "URI:"+uri.String())
+	}
+
+	return names
+}
+
+func summarizeCert(cert *x509.Certificate) *CertSummary {
+	sum := sha256.Sum256(cert.Raw)
+
+	return &CertSummary{
+		Serial:         cert.SerialNumber.String(),
+		Subject:        cert.Subject.String(),
+		Issuer:         cert.Issuer.String(),
+		SANs:           subjectAltNames(cert),
+		NotBefore:      cert.NotBefore.UTC(),
+		NotAfter:       cert.NotAfter.UTC(),
+		KeyFingerprint: keyFingerprint(cert.PublicKey),
+		Fingerprint:    "SHA256:" + hex.EncodeToString(sum[:]),
+	}
+}
+
+func diffSummaries(before, after *CertSummary) []FieldChange {
+	var changes []FieldChange
+
+	compare := func(field, beforeValue, afterValue string) {
+		if beforeValue != afterValue {
+			changes = append(changes, FieldChange{Field: field, Old: beforeValue, New: afterValue})
+		}
+	}
+
+	compare("serial", before.Serial, after.Serial)
+	compare("subject", before.Subject, after.Subject)
+	compare("issuer", before.Issuer, after.Issuer)
+	compare("SANs", strings.Join(before.SANs, ", "), strings.Join(after.SANs, ", "))
+	compare("not before", before.NotBefore.Format(time.RFC3339), after.NotBefore.Format(time.RFC3339))
+	compare("not after", before.NotAfter.Format(time.RFC3339), after.NotAfter.Format(time.RFC3339))
+	compare("key", before.KeyFingerprint, after.KeyFingerprint)
+
+	if len(changes) == 0 {
+		compare("certificate", before.Fingerprint, after.Fingerprint)
+	}
+
+	return changes
+}
+
+// DiffChains compares two chains position by position, and reports the certificates that were added, removed or
+// changed. Use it to describe the differences found by Match.
+func DiffChains(a, b []*x509.Certificate) *ChainDiff {
+	diff := &ChainDiff{Diffs: []*CertDiff{}}
+
+	for pos := range max(len(a), len(b)) {
+		switch {
+		case pos >= len(a):
+			diff.Diffs = append(diff.Diffs, &CertDiff{Position: pos, Kind: CertDiffAdded, New: summarizeCert(b[pos])})
+		case pos >= len(b):
+			diff.Diffs = append(diff.Diffs, &CertDiff{Position: pos, Kind: CertDiffRemoved, Old: summarizeCert(a[pos])})
+		case !a[pos].Equal(b[pos]):
+			before, after := summarizeCert(a[pos]), summarizeCert(b[pos])
+
+			diff.Diffs = append(diff.Diffs, &CertDiff{
+				Position: pos,
+				Kind:     CertDiffChanged,
+				Old:      before,
+				New:      after,
+				Changes:  diffSummaries(before, after),
+			})
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,85 @@
+package certdeck_test
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	testcerts "github.com/a-novel-kit/certdeck/internal/certs"
+)
+
+func TestDiffChains(t *testing.T) {
+	t.Run("Equal", func(t *testing.T) {
+		diff := certdeck.DiffChains(
+			[]*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert},
+			[]*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert},
+		)
+		require.True(t, diff.Empty())
+		require.Equal(t, "chains are equal", diff.String())
+
+		data, err := json.Marshal(diff)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"diffs":[]}`, string(data))
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		diff := certdeck.DiffChains(
+			[]*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert},
+			[]*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain3Cert, testcerts.CACert},
+		)
+		require.False(t, diff.Empty())
+		require.Len(t, diff.Diffs, 2)
+
+		changed := diff.Diffs[0]
+		require.Equal(t, 1, changed.Position)
+		require.Equal(t, certdeck.CertDiffChanged, changed.Kind)
+		require.Equal(t, testcerts.Chain2Cert.SerialNumber.String(), changed.Old.Serial)
+		require.Equal(t, testcerts.Chain3Cert.SerialNumber.String(), changed.New.Serial)
+		require.Equal(t, []string{"DNS:www.example.com"}, changed.New.SANs)
+
+		fields := lo.Map(changed.Changes, func(item certdeck.FieldChange, _ int) string { return item.Field })
+		require.Contains(t, fields, "serial")
+		require.Contains(t, fields, "key")
+		// All fixtures share the same names.
+		require.NotContains(t, fields, "subject")
+		require.NotContains(t, fields, "certificate")
+
+		added := diff.Diffs[1]
+		require.Equal(t, 2, added.Position)
+		require.Equal(t, certdeck.CertDiffAdded, added.Kind)
+		require.Nil(t, added.Old)
+		require.Equal(t, testcerts.CACert.SerialNumber.String(), added.New.Serial)
+
+		text := diff.String()
+		require.Contains(t, text, "certificate 1 changed: CN=www.example.com (serial "+changed.Old.Serial+")")
+		require.Contains(t, text, "\n\tserial: "+changed.Old.Serial+" -> "+changed.New.Serial)
+		require.Contains(t, text, "\ncertificate 2 added: CN=www.example.com (serial "+added.New.Serial+")")
+
+		data, err := json.Marshal(diff)
+		require.NoError(t, err)
+
+		var decoded certdeck.ChainDiff
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Equal(t, diff, &decoded)
+	})
+
+	t.Run("Removed", func(t *testing.T) {
+		diff := certdeck.DiffChains(
+			[]*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert},
+			[]*x509.Certificate{testcerts.Chain1Cert},
+		)
+		require.Len(t, diff.Diffs, 1)
+		require.Equal(t, certdeck.CertDiffRemoved, diff.Diffs[0].Kind)
+		require.Equal(t, testcerts.Chain2Cert.SerialNumber.String(), diff.Diffs[0].Old.Serial)
+		require.Nil(t, diff.Diffs[0].New)
+		require.Equal(
+			t,
+			"certificate 1 removed: CN=www.example.com (serial "+diff.Diffs[0].Old.Serial+")",
+			diff.String(),
+		)
+	})
+}
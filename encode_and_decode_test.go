@@ -1,8 +1,15 @@
 package certdeck_test
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -61,6 +68,40 @@ func TestBase64(t *testing.T) {
 	require.NoError(t, certdeck.Match(certs, decoded))
 }
 
+func TestPKCS7(t *testing.T) {
+	certs := []*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert}
+
+	der, err := certdeck.CertsToPKCS7(certs...)
+	require.NoError(t, err)
+	// Same output as "openssl crl2pkcs7 -nocrl".
+	require.Equal(t, testcerts.ChainP7BDER, der)
+
+	decoded, err := certdeck.PKCS7ToCerts(der)
+	require.NoError(t, err)
+	require.NoError(t, certdeck.Match(certs, decoded))
+
+	pemData, err := certdeck.CertsToPKCS7PEM(certs...)
+	require.NoError(t, err)
+	require.Equal(t, testcerts.ChainP7BPEM, pemData)
+
+	decoded, err = certdeck.PKCS7PEMToCerts(pemData)
+	require.NoError(t, err)
+	require.NoError(t, certdeck.Match(certs, decoded))
+
+	empty, err := certdeck.CertsToPKCS7()
+	require.NoError(t, err)
+
+	decoded, err = certdeck.PKCS7ToCerts(empty)
+	require.NoError(t, err)
+	require.Empty(t, decoded)
+
+	_, err = certdeck.PKCS7ToCerts(testcerts.Chain1Cert.Raw)
+	require.ErrorIs(t, err, certdeck.ErrInvalidPKCS7)
+
+	_, err = certdeck.PKCS7PEMToCerts(testcerts.Chain1CertPEM)
+	require.ErrorIs(t, err, certdeck.ErrInvalidPKCS7)
+}
+
 func TestKeyDER(t *testing.T) {
 	key := testcerts.Chain1Key
 
@@ -82,3 +123,96 @@ func TestKeyPEM(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, key.(*rsa.PrivateKey).Equal(decoded))
 }
+
+func TestCSR(t *testing.T) {
+	raw, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "foo"},
+		DNSNames: []string{"foo.local"},
+	}, testcerts.Chain1Key)
+	require.NoError(t, err)
+
+	csr, err := certdeck.DERToCSR(raw)
+	require.NoError(t, err)
+
+	decoded, err := certdeck.PEMToCSR(certdeck.CSRToPEM(csr))
+	require.NoError(t, err)
+	require.Equal(t, raw, certdeck.CSRToDER(decoded))
+
+	decoded, err = certdeck.PEMOrDERToCSR(certdeck.CSRToDER(csr))
+	require.NoError(t, err)
+	require.Equal(t, raw, decoded.Raw)
+
+	_, err = certdeck.PEMToCSR(testcerts.Chain1CertPEM)
+	require.Error(t, err)
+}
+
+func TestKeyFormats(t *testing.T) {
+	rsaKey := testcerts.Chain1Key.(*rsa.PrivateKey)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+
+		key    crypto.Signer
+		format certdeck.KeyFormat
+
+		expectPEMType string
+		expectErr     error
+	}{
+		{name: "RSA auto", key: rsaKey, format: certdeck.KeyFormatAuto, expectPEMType: "RSA PRIVATE KEY"},
+		{name: "RSA PKCS#1", key: rsaKey, format: certdeck.KeyFormatPKCS1, expectPEMType: "RSA PRIVATE KEY"},
+		{name: "RSA PKCS#8", key: rsaKey, format: certdeck.KeyFormatPKCS8, expectPEMType: "PRIVATE KEY"},
+		{name: "RSA SEC1", key: rsaKey, format: certdeck.KeyFormatSEC1, expectErr: certdeck.ErrUnsupportedKeyFormat},
+		{name: "ECDSA auto", key: ecKey, format: certdeck.KeyFormatAuto, expectPEMType: "EC PRIVATE KEY"},
+		{name: "ECDSA SEC1", key: ecKey, format: certdeck.KeyFormatSEC1, expectPEMType: "EC PRIVATE KEY"},
+		{name: "ECDSA PKCS#8", key: ecKey, format: certdeck.KeyFormatPKCS8, expectPEMType: "PRIVATE KEY"},
+		{name: "ECDSA PKCS#1", key: ecKey, format: certdeck.KeyFormatPKCS1, expectErr: certdeck.ErrUnsupportedKeyFormat},
+		{name: "ED25519 auto", key: edKey, format: certdeck.KeyFormatAuto, expectPEMType: "PRIVATE KEY"},
+		{name: "ED25519 PKCS#8", key: edKey, format: certdeck.KeyFormatPKCS8, expectPEMType: "PRIVATE KEY"},
+		{name: "ED25519 SEC1", key: edKey, format: certdeck.KeyFormatSEC1, expectErr: certdeck.ErrUnsupportedKeyFormat},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			der, err := certdeck.KeyToDERWithFormat(testCase.key, testCase.format)
+			require.ErrorIs(t, err, testCase.expectErr)
+
+			pemData, pemErr := certdeck.KeyToPEMWithFormat(testCase.key, testCase.format)
+			require.ErrorIs(t, pemErr, testCase.expectErr)
+
+			if testCase.expectErr != nil {
+				return
+			}
+
+			decoded, err := certdeck.DERToKey(der)
+			require.NoError(t, err)
+			require.True(t, testCase.key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(decoded.Public()))
+
+			block, _ := pem.Decode(pemData)
+			require.NotNil(t, block)
+			require.Equal(t, testCase.expectPEMType, block.Type)
+
+			decoded, err = certdeck.PEMToKey(pemData)
+			require.NoError(t, err)
+			require.True(t, testCase.key.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(decoded.Public()))
+		})
+	}
+
+	t.Run("unexpected block type", func(t *testing.T) {
+		_, err := certdeck.PEMToKey(testcerts.Chain1CertPEM)
+		require.ErrorIs(t, err, certdeck.ErrUnsupportedKeyFormat)
+	})
+
+	t.Run("mismatched block type", func(t *testing.T) {
+		der, err := certdeck.KeyToDERWithFormat(ecKey, certdeck.KeyFormatSEC1)
+		require.NoError(t, err)
+
+		_, err = certdeck.PEMToKey(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}))
+		require.Error(t, err)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"errors"
+	"fmt"
 )
 
 var ErrUnsupportedKeyFormat = errors.New("unsupported CertKey format")
@@ -57,6 +58,24 @@ func CertsToPEMInline(certificates ...*x509.Certificate) []byte {
 	return pemCerts
 }
 
+// CertsToPKCS7 encodes certificates as a DER PKCS#7 certs-only bundle (.p7b), in the given order.
+func CertsToPKCS7(certificates ...*x509.Certificate) ([]byte, error) {
+	return marshalPKCS7(certificates)
+}
+
+// CertsToPKCS7PEM encodes certificates as a PEM PKCS#7 certs-only bundle (.p7b), in the given order.
+func CertsToPKCS7PEM(certificates ...*x509.Certificate) ([]byte, error) {
+	der, err := marshalPKCS7(certificates)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  pemTypePKCS7,
+		Bytes: der,
+	}), nil
+}
+
 func CertsToBase64(certificates ...*x509.Certificate) []string {
 	base64Certs := make([]string, len(certificates))
 
@@ -67,38 +86,128 @@ func CertsToBase64(certificates ...*x509.Certificate) []string {
 	return base64Certs
 }
 
-func KeyToDER(key any) ([]byte, error) {
-	switch keyT := key.(type) {
+func CSRToDER(csr *x509.CertificateRequest) []byte {
+	return csr.Raw
+}
+
+func CSRToPEM(csr *x509.CertificateRequest) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  pemTypeCSR,
+		Bytes: csr.Raw,
+	})
+}
+
+func CRLToDER(crl *x509.RevocationList) []byte {
+	return crl.Raw
+}
+
+func CRLToPEM(crl *x509.RevocationList) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  pemTypeCRL,
+		Bytes: crl.Raw,
+	})
+}
+
+// KeyFormat is the encoding of a private key.
+type KeyFormat int
+
+const (
+	// KeyFormatAuto uses PKCS#1 for RSA keys, SEC1 for ECDSA keys, and PKCS#8 for any other key.
+	KeyFormatAuto KeyFormat = iota
+	// KeyFormatPKCS1 only supports RSA keys. Its PEM block type is "RSA PRIVATE KEY".
+	KeyFormatPKCS1
+	// KeyFormatSEC1 only supports ECDSA keys. Its PEM block type is "EC PRIVATE KEY".
+	KeyFormatSEC1
+	// KeyFormatPKCS8 supports RSA, ECDSA, ED25519 and ECDH keys. Its PEM block type is "PRIVATE KEY".
+	KeyFormatPKCS8
+)
+
+const (
+	pemTypePKCS1 = "RSA PRIVATE KEY"
+	pemTypeSEC1  = "EC PRIVATE KEY"
+	pemTypePKCS8 = "PRIVATE KEY"
+
+	pemTypeCertificate = "CERTIFICATE"
+	pemTypeCSR         = "CERTIFICATE REQUEST"
+	pemTypeCSRLegacy   = "NEW CERTIFICATE REQUEST"
+	pemTypeCRL         = "X509 CRL"
+	pemTypePKCS7       = "PKCS7"
+)
+
+func resolveKeyFormat(key any, format KeyFormat) KeyFormat {
+	if format != KeyFormatAuto {
+		return format
+	}
+
+	switch key.(type) {
 	case *rsa.PrivateKey:
-		return x509.MarshalPKCS1PrivateKey(keyT), nil
+		return KeyFormatPKCS1
 	case *ecdsa.PrivateKey:
-		return x509.MarshalECPrivateKey(keyT)
+		return KeyFormatSEC1
 	default:
-		return nil, ErrUnsupportedKeyFormat
+		return KeyFormatPKCS8
 	}
 }
 
-func KeyToPEM(key any) ([]byte, error) {
-	var block *pem.Block
-	switch keyT := key.(type) {
-	case *rsa.PrivateKey:
-		block = &pem.Block{
-			Type:  "RSA PRIVATE KEY",
-			Bytes: x509.MarshalPKCS1PrivateKey(keyT),
+// KeyToDERWithFormat encodes a private key to DER, using the given format.
+func KeyToDERWithFormat(key any, format KeyFormat) ([]byte, error) {
+	switch resolveKeyFormat(key, format) {
+	case KeyFormatPKCS1:
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: PKCS#1 requires a RSA key, got %T", ErrUnsupportedKeyFormat, key)
 		}
-	case *ecdsa.PrivateKey:
-		encoded, err := x509.MarshalECPrivateKey(keyT)
-		if err != nil {
-			return nil, err
+
+		return x509.MarshalPKCS1PrivateKey(rsaKey), nil
+	case KeyFormatSEC1:
+		ecKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: SEC1 requires an ECDSA key, got %T", ErrUnsupportedKeyFormat, key)
 		}
 
-		block = &pem.Block{
-			Type:  "EC PRIVATE KEY",
-			Bytes: encoded,
+		return x509.MarshalECPrivateKey(ecKey)
+	case KeyFormatPKCS8:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnsupportedKeyFormat, err)
 		}
+
+		return der, nil
 	default:
-		return nil, ErrUnsupportedKeyFormat
+		return nil, fmt.Errorf("%w: unknown format %d", ErrUnsupportedKeyFormat, format)
 	}
+}
 
-	return pem.EncodeToMemory(block), nil
+// KeyToPEMWithFormat encodes a private key to PEM, using the given format.
+func KeyToPEMWithFormat(key any, format KeyFormat) ([]byte, error) {
+	format = resolveKeyFormat(key, format)
+
+	der, err := KeyToDERWithFormat(key, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var blockType string
+	switch format {
+	case KeyFormatPKCS1:
+		blockType = pemTypePKCS1
+	case KeyFormatSEC1:
+		blockType = pemTypeSEC1
+	default:
+		blockType = pemTypePKCS8
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), nil
+}
+
+// KeyToDER encodes a private key to DER. RSA keys are encoded with PKCS#1, ECDSA keys with SEC1, and any other
+// key with PKCS#8.
+func KeyToDER(key any) ([]byte, error) {
+	return KeyToDERWithFormat(key, KeyFormatAuto)
+}
+
+// KeyToPEM encodes a private key to PEM. RSA keys are encoded with PKCS#1, ECDSA keys with SEC1, and any other
+// key with PKCS#8.
+func KeyToPEM(key any) ([]byte, error) {
+	return KeyToPEMWithFormat(key, KeyFormatAuto)
 }
@@ -0,0 +1,581 @@
+package certdeck
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // Used to read keys encrypted with the PBKDF2 default PRF.
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/samber/lo"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	ErrEncryptedKey          = errors.New("private key is encrypted")
+	ErrIncorrectPassphrase   = errors.New("incorrect passphrase")
+	ErrUnsupportedEncryption = errors.New("unsupported private key encryption")
+)
+
+const pemTypeEncryptedPKCS8 = "ENCRYPTED PRIVATE KEY"
+
+const gcmStandardNonceSize = 12
+
+// KeyDerivation is the function used to derive an encryption key from a passphrase.
+type KeyDerivation int
+
+const (
+	// KeyDerivationPBKDF2 uses PBKDF2 with HMAC-SHA256 (RFC 8018).
+	KeyDerivationPBKDF2 KeyDerivation = iota
+	// KeyDerivationScrypt uses scrypt (RFC 7914).
+	KeyDerivationScrypt
+)
+
+// KeyCipher is the cipher used to encrypt a private key.
+type KeyCipher int
+
+// AES-CBC is supported by every tool that reads encrypted PKCS#8 keys. AES-GCM is authenticated, but OpenSSL
+// cannot read keys encrypted with it.
+const (
+	KeyCipherAES256CBC KeyCipher = iota
+	KeyCipherAES192CBC
+	KeyCipherAES128CBC
+	KeyCipherAES256GCM
+	KeyCipherAES192GCM
+	KeyCipherAES128GCM
+)
+
+// Default key encryption parameters. The PBKDF2 iterations follow the OWASP recommendation for HMAC-SHA256, and
+// the scrypt parameters match the OpenSSL defaults, which also cap scrypt memory usage at 32MB.
+const (
+	DefaultPBKDF2Iterations = 600_000
+	DefaultScryptN          = 1 << 14
+	DefaultScryptR          = 8
+	DefaultScryptP          = 1
+	DefaultKeySaltSize      = 16
+)
+
+// Maximum key derivation parameters accepted when decrypting a key. They prevent a crafted key from making the
+// decryption hang, or run out of memory. Keys that exceed them are rejected with ErrUnsupportedEncryption.
+const (
+	MaxPBKDF2Iterations = 10_000_000
+	// MaxScryptMemory is the maximum memory used by scrypt, in bytes. It bounds both 128 * N * r and 128 * r * p.
+	MaxScryptMemory = 256 << 20
+	MaxScryptP      = 16
+)
+
+// KeyEncryptionParams configures the encryption of a private key. The zero value uses PBKDF2 with AES-256-CBC,
+// which is the most widely supported combination.
+type KeyEncryptionParams struct {
+	// KDF derives the encryption key from the passphrase.
+	KDF KeyDerivation
+	// Cipher encrypts the private key.
+	Cipher KeyCipher
+
+	// PBKDF2Iterations is the number of PBKDF2 iterations.
+	//
+	// DefaultPBKDF2Iterations is used by default.
+	PBKDF2Iterations int
+
+	// ScryptN is the scrypt cost parameter. It must be a power of 2.
+	//
+	// DefaultScryptN is used by default.
+	ScryptN int
+	// ScryptR is the scrypt block size.
+	//
+	// DefaultScryptR is used by default.
+	ScryptR int
+	// ScryptP is the scrypt parallelization parameter.
+	//
+	// DefaultScryptP is used by default.
+	ScryptP int
+
+	// SaltSize is the size of the random salt, in bytes.
+	//
+	// DefaultKeySaltSize is used by default.
+	SaltSize int
+}
+
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidScrypt = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA224 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 8}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA384 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 10}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidAES128GCM = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 6}
+	oidAES192GCM = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 26}
+	oidAES256GCM = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 46}
+)
+
+type keyCipherInfo struct {
+	oid     asn1.ObjectIdentifier
+	keySize int
+	gcm     bool
+}
+
+var keyCiphers = map[KeyCipher]keyCipherInfo{
+	KeyCipherAES256CBC: {oid: oidAES256CBC, keySize: 32},
+	KeyCipherAES192CBC: {oid: oidAES192CBC, keySize: 24},
+	KeyCipherAES128CBC: {oid: oidAES128CBC, keySize: 16},
+	KeyCipherAES256GCM: {oid: oidAES256GCM, keySize: 32, gcm: true},
+	KeyCipherAES192GCM: {oid: oidAES192GCM, keySize: 24, gcm: true},
+	KeyCipherAES128GCM: {oid: oidAES128GCM, keySize: 16, gcm: true},
+}
+
+type pbkdf2PRF struct {
+	oid  asn1.ObjectIdentifier
+	hash func() hash.Hash
+}
+
+var pbkdf2PRFs = []pbkdf2PRF{
+	{oid: oidHMACWithSHA1, hash: sha1.New},
+	{oid: oidHMACWithSHA224, hash: sha256.New224},
+	{oid: oidHMACWithSHA256, hash: sha256.New},
+	{oid: oidHMACWithSHA384, hash: sha512.New384},
+	{oid: oidHMACWithSHA512, hash: sha512.New},
+}
+
+// ASN.1 structures from RFC 5208, RFC 8018, RFC 7914 and RFC 5084.
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+type scryptParams struct {
+	Salt                     []byte
+	CostParameter            int
+	BlockSize                int
+	ParallelizationParameter int
+	KeyLength                int `asn1:"optional"`
+}
+
+type gcmParams struct {
+	Nonce  []byte
+	ICVLen int `asn1:"optional,default:12"`
+}
+
+func marshalAlgorithm(oid asn1.ObjectIdentifier, params any) (pkix.AlgorithmIdentifier, error) {
+	raw, err := asn1.Marshal(params)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+
+	return pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.RawValue{FullBytes: raw}}, nil
+}
+
+func unmarshalParams(algorithm pkix.AlgorithmIdentifier, params any) error {
+	rest, err := asn1.Unmarshal(algorithm.Parameters.FullBytes, params)
+	if err != nil {
+		return fmt.Errorf("%w: parameters of %s: %w", ErrUnsupportedEncryption, algorithm.Algorithm, err)
+	}
+
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: trailing data in parameters of %s", ErrUnsupportedEncryption, algorithm.Algorithm)
+	}
+
+	return nil
+}
+
+func deriveEncryptionKey(
+	passphrase []byte, keySize int, params *KeyEncryptionParams,
+) ([]byte, pkix.AlgorithmIdentifier, error) {
+	salt := make([]byte, lo.CoalesceOrEmpty(params.SaltSize, DefaultKeySaltSize))
+	if _, err := rand.Read(salt); err != nil {
+		return nil, pkix.AlgorithmIdentifier{}, fmt.Errorf("generate salt: %w", err)
+	}
+
+	switch params.KDF {
+	case KeyDerivationPBKDF2:
+		iterations := lo.CoalesceOrEmpty(params.PBKDF2Iterations, DefaultPBKDF2Iterations)
+		key := pbkdf2.Key(passphrase, salt, iterations, keySize, sha256.New)
+
+		algorithm, err := marshalAlgorithm(oidPBKDF2, pbkdf2Params{
+			Salt:           salt,
+			IterationCount: iterations,
+			PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+		})
+
+		return key, algorithm, err
+	case KeyDerivationScrypt:
+		costParameter := lo.CoalesceOrEmpty(params.ScryptN, DefaultScryptN)
+		blockSize := lo.CoalesceOrEmpty(params.ScryptR, DefaultScryptR)
+		parallelization := lo.CoalesceOrEmpty(params.ScryptP, DefaultScryptP)
+
+		key, err := scrypt.Key(passphrase, salt, costParameter, blockSize, parallelization, keySize)
+		if err != nil {
+			return nil, pkix.AlgorithmIdentifier{}, fmt.Errorf("scrypt: %w", err)
+		}
+
+		algorithm, err := marshalAlgorithm(oidScrypt, scryptParams{
+			Salt:                     salt,
+			CostParameter:            costParameter,
+			BlockSize:                blockSize,
+			ParallelizationParameter: parallelization,
+		})
+
+		return key, algorithm, err
+	default:
+		return nil, pkix.AlgorithmIdentifier{}, fmt.Errorf(
+			"%w: unknown key derivation %d", ErrUnsupportedEncryption, params.KDF,
+		)
+	}
+}
+
+func encryptWithCipher(
+	plaintext, key []byte, info keyCipherInfo,
+) ([]byte, pkix.AlgorithmIdentifier, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, pkix.AlgorithmIdentifier{}, fmt.Errorf("create cipher: %w", err)
+	}
+
+	if info.gcm {
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, pkix.AlgorithmIdentifier{}, fmt.Errorf("create gcm: %w", err)
+		}
+
+		nonce := make([]byte, aead.NonceSize())
+		if _, err = rand.Read(nonce); err != nil {
+			return nil, pkix.AlgorithmIdentifier{}, fmt.Errorf("generate nonce: %w", err)
+		}
+
+		algorithm, err := marshalAlgorithm(info.oid, gcmParams{Nonce: nonce, ICVLen: aead.Overhead()})
+
+		return aead.Seal(nil, nonce, plaintext, nil), algorithm, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err = rand.Read(iv); err != nil {
+		return nil, pkix.AlgorithmIdentifier{}, fmt.Errorf("generate iv: %w", err)
+	}
+
+	// PKCS#7 padding.
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(bytes.Clone(plaintext), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	algorithm, err := marshalAlgorithm(info.oid, iv)
+
+	return ciphertext, algorithm, err
+}
+
+// KeyToEncryptedDER encodes a private key to an encrypted PKCS#8 structure (EncryptedPrivateKeyInfo), using
+// PBES2.
+//
+// If params is nil, the default parameters are used.
+func KeyToEncryptedDER(key any, passphrase []byte, params *KeyEncryptionParams) ([]byte, error) {
+	if params == nil {
+		params = &KeyEncryptionParams{}
+	}
+
+	info, ok := keyCiphers[params.Cipher]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown cipher %d", ErrUnsupportedEncryption, params.Cipher)
+	}
+
+	plaintext, err := KeyToDERWithFormat(key, KeyFormatPKCS8)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptionKey, kdf, err := deriveEncryptionKey(passphrase, info.keySize, params)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+
+	ciphertext, scheme, err := encryptWithCipher(plaintext, encryptionKey, info)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt key: %w", err)
+	}
+
+	algorithm, err := marshalAlgorithm(oidPBES2, pbes2Params{KeyDerivationFunc: kdf, EncryptionScheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("marshal pbes2 parameters: %w", err)
+	}
+
+	der, err := asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: algorithm, EncryptedData: ciphertext})
+	if err != nil {
+		return nil, fmt.Errorf("marshal encrypted key: %w", err)
+	}
+
+	return der, nil
+}
+
+// KeyToEncryptedPEM encodes a private key to an "ENCRYPTED PRIVATE KEY" PEM block. See KeyToEncryptedDER.
+func KeyToEncryptedPEM(key any, passphrase []byte, params *KeyEncryptionParams) ([]byte, error) {
+	der, err := KeyToEncryptedDER(key, passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypeEncryptedPKCS8, Bytes: der}), nil
+}
+
+func decryptionKey(passphrase []byte, kdf pkix.AlgorithmIdentifier, keySize int) ([]byte, error) {
+	switch {
+	case kdf.Algorithm.Equal(oidPBKDF2):
+		var params pbkdf2Params
+		if err := unmarshalParams(kdf, &params); err != nil {
+			return nil, err
+		}
+
+		if params.KeyLength != 0 && params.KeyLength != keySize {
+			return nil, fmt.Errorf("%w: key length %d does not match the cipher", ErrUnsupportedEncryption, params.KeyLength)
+		}
+
+		// HMAC-SHA1 is the default PRF of PBKDF2.
+		prf := sha1.New
+		if len(params.PRF.Algorithm) > 0 {
+			found, ok := lo.Find(pbkdf2PRFs, func(item pbkdf2PRF) bool {
+				return item.oid.Equal(params.PRF.Algorithm)
+			})
+			if !ok {
+				return nil, fmt.Errorf("%w: pbkdf2 prf %s", ErrUnsupportedEncryption, params.PRF.Algorithm)
+			}
+
+			prf = found.hash
+		}
+
+		if params.IterationCount > MaxPBKDF2Iterations {
+			return nil, fmt.Errorf(
+				"%w: pbkdf2 iteration count %d exceeds %d",
+				ErrUnsupportedEncryption, params.IterationCount, MaxPBKDF2Iterations,
+			)
+		}
+
+		return pbkdf2.Key(passphrase, params.Salt, params.IterationCount, keySize, prf), nil
+	case kdf.Algorithm.Equal(oidScrypt):
+		var params scryptParams
+		if err := unmarshalParams(kdf, &params); err != nil {
+			return nil, err
+		}
+
+		if params.KeyLength != 0 && params.KeyLength != keySize {
+			return nil, fmt.Errorf("%w: key length %d does not match the cipher", ErrUnsupportedEncryption, params.KeyLength)
+		}
+
+		costParameter, blockSize, parallelization := params.CostParameter, params.BlockSize, params.ParallelizationParameter
+
+		// Zero values make scrypt panic.
+		if costParameter < 1 || blockSize < 1 || parallelization < 1 || parallelization > MaxScryptP {
+			return nil, fmt.Errorf(
+				"%w: invalid scrypt parameters N=%d r=%d p=%d",
+				ErrUnsupportedEncryption, costParameter, blockSize, parallelization,
+			)
+		}
+
+		// scrypt allocates 128 * N * r and 128 * r * p bytes. Compare them with a division, so large values do not
+		// overflow.
+		if maxFactor := MaxScryptMemory / 128 / blockSize; costParameter > maxFactor || parallelization > maxFactor {
+			return nil, fmt.Errorf(
+				"%w: scrypt parameters N=%d r=%d p=%d use more than %d bytes",
+				ErrUnsupportedEncryption, costParameter, blockSize, parallelization, MaxScryptMemory,
+			)
+		}
+
+		key, err := scrypt.Key(
+			passphrase, params.Salt, costParameter, blockSize, parallelization, keySize,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%w: scrypt: %w", ErrUnsupportedEncryption, err)
+		}
+
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: key derivation %s", ErrUnsupportedEncryption, kdf.Algorithm)
+	}
+}
+
+func decryptWithCipher(ciphertext, key []byte, scheme pkix.AlgorithmIdentifier, info keyCipherInfo) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	if info.gcm {
+		var params gcmParams
+		if err = unmarshalParams(scheme, &params); err != nil {
+			return nil, err
+		}
+
+		if len(params.Nonce) != gcmStandardNonceSize {
+			return nil, fmt.Errorf("%w: invalid gcm nonce length %d", ErrUnsupportedEncryption, len(params.Nonce))
+		}
+
+		aead, err := cipher.NewGCMWithTagSize(block, params.ICVLen)
+		if err != nil {
+			return nil, fmt.Errorf("%w: gcm: %w", ErrUnsupportedEncryption, err)
+		}
+
+		plaintext, err := aead.Open(nil, params.Nonce, ciphertext, nil)
+		if err != nil {
+			return nil, ErrIncorrectPassphrase
+		}
+
+		return plaintext, nil
+	}
+
+	var iv []byte
+	if err = unmarshalParams(scheme, &iv); err != nil {
+		return nil, err
+	}
+
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("%w: invalid iv length %d", ErrUnsupportedEncryption, len(iv))
+	}
+
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: invalid ciphertext length %d", ErrUnsupportedEncryption, len(ciphertext))
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	// A wrong passphrase almost always results in an invalid padding.
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, ErrIncorrectPassphrase
+	}
+
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, ErrIncorrectPassphrase
+		}
+	}
+
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// EncryptedDERToKey decrypts an encrypted PKCS#8 private key (EncryptedPrivateKeyInfo). Only PBES2 is supported,
+// with PBKDF2 or scrypt, and AES-CBC or AES-GCM.
+func EncryptedDERToKey(data, passphrase []byte) (crypto.Signer, error) {
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(data, &info); err != nil || len(rest) > 0 {
+		return nil, fmt.Errorf("%w: not an encrypted PKCS#8 key", ErrUnsupportedKeyFormat)
+	}
+
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("%w: encryption scheme %s", ErrUnsupportedEncryption, info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if err := unmarshalParams(info.Algorithm, &params); err != nil {
+		return nil, err
+	}
+
+	cipherInfo, ok := lo.Find(lo.Values(keyCiphers), func(item keyCipherInfo) bool {
+		return item.oid.Equal(params.EncryptionScheme.Algorithm)
+	})
+	if !ok {
+		return nil, fmt.Errorf("%w: cipher %s", ErrUnsupportedEncryption, params.EncryptionScheme.Algorithm)
+	}
+
+	key, err := decryptionKey(passphrase, params.KeyDerivationFunc, cipherInfo.keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := decryptWithCipher(info.EncryptedData, key, params.EncryptionScheme, cipherInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	rawKey, err := x509.ParsePKCS8PrivateKey(plaintext)
+	if err != nil {
+		// Padding checks let about 1 in 256 wrong passphrases through.
+		return nil, fmt.Errorf("%w: %w", ErrIncorrectPassphrase, err)
+	}
+
+	signer, ok := rawKey.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedKeyFormat
+	}
+
+	return signer, nil
+}
+
+// encryptedPEMBlockToKey parses a private key from a PEM block, decrypting it if needed.
+func encryptedPEMBlockToKey(block *pem.Block, passphrase []byte) (crypto.Signer, error) {
+	if block.Type == pemTypeEncryptedPKCS8 {
+		return EncryptedDERToKey(block.Bytes, passphrase)
+	}
+
+	//nolint:staticcheck // Legacy encryption is insecure by design, but still found in the wild.
+	if x509.IsEncryptedPEMBlock(block) {
+		//nolint:staticcheck
+		der, err := x509.DecryptPEMBlock(block, passphrase)
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, ErrIncorrectPassphrase
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnsupportedEncryption, err)
+		}
+
+		return pemBlockToKey(&pem.Block{Type: block.Type, Bytes: der})
+	}
+
+	return pemBlockToKey(block)
+}
+
+// EncryptedPEMToKey parses a PEM encoded private key, decrypting it with the passphrase.
+//
+// Both "ENCRYPTED PRIVATE KEY" blocks (PKCS#8 with PBES2) and legacy OpenSSL blocks, with a
+// "Proc-Type: 4,ENCRYPTED" header, are supported. Unencrypted keys are returned as is.
+func EncryptedPEMToKey(data, passphrase []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("decode pem block: no block found")
+	}
+
+	return encryptedPEMBlockToKey(block, passphrase)
+}
+
+// EncryptedPEMOrDERToKey is like EncryptedPEMToKey, but also accepts DER encoded keys, encrypted or not.
+func EncryptedPEMOrDERToKey(data, passphrase []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block != nil {
+		return encryptedPEMBlockToKey(block, passphrase)
+	}
+
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(data, &info); err == nil && len(rest) == 0 && len(info.EncryptedData) > 0 {
+		return EncryptedDERToKey(data, passphrase)
+	}
+
+	return DERToKey(data)
+}
@@ -0,0 +1,253 @@
+package certdeck_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	testcerts "github.com/a-novel-kit/certdeck/internal/certs"
+)
+
+func TestEncryptedKeyFixtures(t *testing.T) {
+	passphrase := []byte(testcerts.EncryptedKeyPassphrase)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "PBES2 PBKDF2", data: testcerts.EncryptedChain1KeyPBKDF2PEM},
+		{name: "PBES2 scrypt", data: testcerts.EncryptedChain1KeyScryptPEM},
+		{name: "Legacy", data: testcerts.EncryptedChain1KeyLegacyPEM},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			key, err := certdeck.EncryptedPEMToKey(testCase.data, passphrase)
+			require.NoError(t, err)
+			require.True(t, testcerts.Chain1Key.(*rsa.PrivateKey).Equal(key))
+
+			_, err = certdeck.EncryptedPEMToKey(testCase.data, []byte("wrong"))
+			require.ErrorIs(t, err, certdeck.ErrIncorrectPassphrase)
+
+			_, err = certdeck.PEMToKey(testCase.data)
+			require.ErrorIs(t, err, certdeck.ErrEncryptedKey)
+		})
+	}
+}
+
+func TestEncryptedKeyRoundTrip(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	passphrase := []byte("correct horse battery staple")
+
+	testCases := []struct {
+		name string
+
+		key    crypto.Signer
+		params *certdeck.KeyEncryptionParams
+	}{
+		{
+			name:   "Defaults",
+			key:    ecdsaKey,
+			params: nil,
+		},
+		{
+			name:   "PBKDF2/AES-128-CBC",
+			key:    ed25519Key,
+			params: &certdeck.KeyEncryptionParams{Cipher: certdeck.KeyCipherAES128CBC, PBKDF2Iterations: 1000},
+		},
+		{
+			name:   "PBKDF2/AES-256-GCM",
+			key:    ecdsaKey,
+			params: &certdeck.KeyEncryptionParams{Cipher: certdeck.KeyCipherAES256GCM, PBKDF2Iterations: 1000},
+		},
+		{
+			name: "Scrypt/AES-192-GCM",
+			key:  ed25519Key,
+			params: &certdeck.KeyEncryptionParams{
+				KDF:     certdeck.KeyDerivationScrypt,
+				Cipher:  certdeck.KeyCipherAES192GCM,
+				ScryptN: 1 << 10,
+			},
+		},
+		{
+			name:   "Scrypt/AES-256-CBC",
+			key:    testcerts.Chain1Key,
+			params: &certdeck.KeyEncryptionParams{KDF: certdeck.KeyDerivationScrypt, ScryptN: 1 << 10},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			encrypted, err := certdeck.KeyToEncryptedPEM(testCase.key, passphrase, testCase.params)
+			require.NoError(t, err)
+
+			block, _ := pem.Decode(encrypted)
+			require.NotNil(t, block)
+			require.Equal(t, "ENCRYPTED PRIVATE KEY", block.Type)
+
+			decoded, err := certdeck.EncryptedPEMToKey(encrypted, passphrase)
+			require.NoError(t, err)
+			require.True(t, testCase.key.(interface{ Equal(crypto.PrivateKey) bool }).Equal(decoded))
+
+			decoded, err = certdeck.EncryptedPEMOrDERToKey(block.Bytes, passphrase)
+			require.NoError(t, err)
+			require.True(t, testCase.key.(interface{ Equal(crypto.PrivateKey) bool }).Equal(decoded))
+
+			_, err = certdeck.EncryptedPEMToKey(encrypted, []byte("wrong"))
+			require.ErrorIs(t, err, certdeck.ErrIncorrectPassphrase)
+		})
+	}
+}
+
+func TestEncryptedKeyUnencrypted(t *testing.T) {
+	// Plain keys are accepted by the encrypted decoders, so callers do not need to know in advance.
+	key, err := certdeck.EncryptedPEMToKey(testcerts.Chain1KeypairPEM, []byte("unused"))
+	require.NoError(t, err)
+	require.True(t, testcerts.Chain1Key.(*rsa.PrivateKey).Equal(key))
+
+	der, err := certdeck.KeyToDER(testcerts.Chain1Key)
+	require.NoError(t, err)
+
+	key, err = certdeck.EncryptedPEMOrDERToKey(der, []byte("unused"))
+	require.NoError(t, err)
+	require.True(t, testcerts.Chain1Key.(*rsa.PrivateKey).Equal(key))
+}
+
+func TestEncryptedKeyUnsupportedParams(t *testing.T) {
+	_, err := certdeck.KeyToEncryptedPEM(testcerts.Chain1Key, []byte("foo"), &certdeck.KeyEncryptionParams{
+		Cipher: certdeck.KeyCipher(42),
+	})
+	require.ErrorIs(t, err, certdeck.ErrUnsupportedEncryption)
+
+	_, err = certdeck.KeyToEncryptedPEM(testcerts.Chain1Key, []byte("foo"), &certdeck.KeyEncryptionParams{
+		KDF: certdeck.KeyDerivation(42),
+	})
+	require.ErrorIs(t, err, certdeck.ErrUnsupportedEncryption)
+}
+
+// newEncryptedKeyWithKDF builds an AES-256-CBC encrypted PKCS#8 key, with arbitrary key derivation parameters. The
+// encrypted data is garbage, as decryption must fail before reaching it.
+func newEncryptedKeyWithKDF(t *testing.T, oid asn1.ObjectIdentifier, params any) []byte {
+	t.Helper()
+
+	algorithm := func(oid asn1.ObjectIdentifier, params any) pkix.AlgorithmIdentifier {
+		der, err := asn1.Marshal(params)
+		require.NoError(t, err)
+
+		return pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.RawValue{FullBytes: der}}
+	}
+
+	pbes2 := struct {
+		KeyDerivationFunc pkix.AlgorithmIdentifier
+		EncryptionScheme  pkix.AlgorithmIdentifier
+	}{
+		KeyDerivationFunc: algorithm(oid, params),
+		EncryptionScheme:  algorithm(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}, make([]byte, 16)),
+	}
+
+	der, err := asn1.Marshal(struct {
+		Algorithm     pkix.AlgorithmIdentifier
+		EncryptedData []byte
+	}{
+		Algorithm:     algorithm(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}, pbes2),
+		EncryptedData: make([]byte, 32),
+	})
+	require.NoError(t, err)
+
+	return der
+}
+
+func TestEncryptedKeyLimits(t *testing.T) {
+	type pbkdf2Params struct {
+		Salt           []byte
+		IterationCount int
+	}
+
+	type scryptParams struct {
+		Salt                     []byte
+		CostParameter            int
+		BlockSize                int
+		ParallelizationParameter int
+	}
+
+	oidPBKDF2 := asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidScrypt := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11591, 4, 11}
+	salt := make([]byte, 16)
+
+	testCases := []struct {
+		name string
+
+		oid    asn1.ObjectIdentifier
+		params any
+	}{
+		{
+			name:   "PBKDF2 iterations",
+			oid:    oidPBKDF2,
+			params: pbkdf2Params{Salt: salt, IterationCount: certdeck.MaxPBKDF2Iterations + 1},
+		},
+		{
+			name:   "scrypt memory",
+			oid:    oidScrypt,
+			params: scryptParams{Salt: salt, CostParameter: 1 << 24, BlockSize: 8, ParallelizationParameter: 1},
+		},
+		{
+			name:   "scrypt overflow",
+			oid:    oidScrypt,
+			params: scryptParams{Salt: salt, CostParameter: 1 << 62, BlockSize: 1 << 20, ParallelizationParameter: 1},
+		},
+		{
+			name:   "scrypt negative",
+			oid:    oidScrypt,
+			params: scryptParams{Salt: salt, CostParameter: -1, BlockSize: 8, ParallelizationParameter: 1},
+		},
+		{
+			name:   "scrypt zero parallelization",
+			oid:    oidScrypt,
+			params: scryptParams{Salt: salt, CostParameter: 1 << 10, BlockSize: 8, ParallelizationParameter: 0},
+		},
+		{
+			name:   "scrypt zero block size",
+			oid:    oidScrypt,
+			params: scryptParams{Salt: salt, CostParameter: 1 << 10, BlockSize: 0, ParallelizationParameter: 1},
+		},
+		{
+			name:   "scrypt block memory",
+			oid:    oidScrypt,
+			params: scryptParams{Salt: salt, CostParameter: 2, BlockSize: 1 << 20, ParallelizationParameter: 16},
+		},
+		{
+			name:   "scrypt parallelization",
+			oid:    oidScrypt,
+			params: scryptParams{Salt: salt, CostParameter: 1 << 10, BlockSize: 8, ParallelizationParameter: 1 << 20},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			der := newEncryptedKeyWithKDF(t, testCase.oid, testCase.params)
+
+			_, err := certdeck.EncryptedDERToKey(der, []byte("foo"))
+			require.ErrorIs(t, err, certdeck.ErrUnsupportedEncryption)
+
+			_, err = certdeck.EncryptedPEMOrDERToKey(
+				pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der}), []byte("foo"),
+			)
+			require.ErrorIs(t, err, certdeck.ErrUnsupportedEncryption)
+		})
+	}
+}
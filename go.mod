@@ -5,6 +5,9 @@ go 1.23.4
 require (
 	github.com/samber/lo v1.47.0
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/crypto v0.31.0
+	golang.org/x/sync v0.10.0
+	software.sslmate.com/src/go-pkcs12 v0.7.0
 )
 
 require (
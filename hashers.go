@@ -1,20 +1,85 @@
 package certdeck
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
 )
 
+// KeyIDMethod is the method used to derive a key identifier from a public key.
+type KeyIDMethod int
+
+const (
+	// KeyIDSHA1 is the SHA-1 hash of the subjectPublicKey bits, as described in RFC 5280, section 4.2.1.2
+	// (method 1). This is what OpenSSL and the Go standard library compute.
+	KeyIDSHA1 KeyIDMethod = iota
+	// KeyIDSHA256Truncated is the leftmost 160 bits of the SHA-256 hash of the subjectPublicKey bits, as
+	// described in RFC 7093, section 2 (method 1).
+	KeyIDSHA256Truncated
+)
+
+// subjectPublicKeyBits returns the raw subjectPublicKey bits of a DER encoded SubjectPublicKeyInfo, without
+// the tag, length and unused bits of the BIT STRING.
+func subjectPublicKeyBits(spkiDER []byte) ([]byte, error) {
+	var spki struct {
+		Algorithm asn1.RawValue
+		PublicKey asn1.BitString
+	}
+
+	if _, err := asn1.Unmarshal(spkiDER, &spki); err != nil {
+		return nil, fmt.Errorf("parse subject public key info: %w", err)
+	}
+
+	return spki.PublicKey.RightAlign(), nil
+}
+
+// KeyID derives a key identifier from a public key, to be used as the subject key identifier of a certificate.
+//
+// Unlike the HashRSA, HashECDSA and HashED25519 hashers, the result matches the identifiers computed by other
+// tools, like OpenSSL.
+func KeyID(pub crypto.PublicKey, method KeyIDMethod) ([]byte, error) {
+	spkiDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("marshal public key: %w", err)
+	}
+
+	keyBits, err := subjectPublicKeyBits(spkiDER)
+	if err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case KeyIDSHA1:
+		sum := sha1.Sum(keyBits)
+		return sum[:], nil
+	case KeyIDSHA256Truncated:
+		sum := sha256.Sum256(keyBits)
+		return sum[:20], nil
+	default:
+		return nil, fmt.Errorf("unknown key ID method %d", method)
+	}
+}
+
 // https://www.reddit.com/r/golang/comments/m1sjfs/comment/gqfoq26/
 
+// HashRSA derives a key identifier from the modulus of a RSA public key.
+//
+// Deprecated: the result does not follow RFC 5280. Use KeyID instead.
 func HashRSA(src *rsa.PublicKey) []byte {
 	hasher := sha1.New()
 	hasher.Write(src.N.Bytes())
 	return hasher.Sum(nil)
 }
 
+// HashECDSA derives a key identifier from the coordinates of an ECDSA public key.
+//
+// Deprecated: the result does not follow RFC 5280. Use KeyID instead.
 func HashECDSA(src *ecdsa.PublicKey) []byte {
 	hasher := sha1.New()
 	hasher.Write(src.X.Bytes())
@@ -22,6 +87,9 @@ func HashECDSA(src *ecdsa.PublicKey) []byte {
 	return hasher.Sum(nil)
 }
 
+// HashED25519 derives a key identifier from an ED25519 public key.
+//
+// Deprecated: the result does not follow RFC 5280. Use KeyID instead.
 func HashED25519(src *ed25519.PublicKey) []byte {
 	hasher := sha1.New()
 	hasher.Write(*src)
@@ -0,0 +1,88 @@
+package certdeck_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	"github.com/a-novel-kit/certdeck/internal/certs"
+	certdeckmocks "github.com/a-novel-kit/certdeck/mocks"
+)
+
+func TestKeyID(t *testing.T) {
+	t.Run("matches OpenSSL", func(t *testing.T) {
+		// Those certificates were generated with OpenSSL, which uses RFC 5280 method 1.
+		for _, cert := range []*x509.Certificate{certs.Chain1Cert, certs.Chain2Cert, certs.Chain3Cert} {
+			keyID, err := certdeck.KeyID(cert.PublicKey, certdeck.KeyIDSHA1)
+			require.NoError(t, err)
+			require.Equal(t, cert.SubjectKeyId, keyID)
+		}
+	})
+
+	t.Run("SHA-256 truncated", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		keyID, err := certdeck.KeyID(key.Public(), certdeck.KeyIDSHA256Truncated)
+		require.NoError(t, err)
+		require.Len(t, keyID, 20)
+
+		ecdhKey, err := key.PublicKey.ECDH()
+		require.NoError(t, err)
+
+		sum := sha256.Sum256(ecdhKey.Bytes())
+		require.Equal(t, sum[:20], keyID)
+	})
+
+	t.Run("unsupported key", func(t *testing.T) {
+		_, err := certdeck.KeyID("foo", certdeck.KeyIDSHA1)
+		require.Error(t, err)
+	})
+}
+
+func TestSignerKeyID(t *testing.T) {
+	store := certdeckmocks.NewMockSerialStore(t)
+	store.On("Insert", context.Background(), mock.Anything).Return(nil)
+
+	_, rootKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rootCert, err := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore: store,
+		KeyIDMethod: certdeck.KeyIDSHA256Truncated,
+	}).Sign(context.Background(), rootKey, nil, &certdeck.Template{Exp: time.Hour})
+	require.NoError(t, err)
+
+	expected, err := certdeck.KeyID(rootKey.Public(), certdeck.KeyIDSHA256Truncated)
+	require.NoError(t, err)
+	require.Equal(t, expected, rootCert.SubjectKeyId)
+
+	signer := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore: store,
+		IssuerChain: []*x509.Certificate{rootCert},
+		IssuerKey:   rootKey,
+	})
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	leafCert, err := signer.Sign(
+		context.Background(), leafKey.Public(), nil, &certdeck.Template{Exp: time.Hour, LeafOnly: true},
+	)
+	require.NoError(t, err)
+
+	expected, err = certdeck.KeyID(leafKey.Public(), certdeck.KeyIDSHA1)
+	require.NoError(t, err)
+	require.Equal(t, expected, leafCert.SubjectKeyId)
+	require.Equal(t, rootCert.SubjectKeyId, leafCert.AuthorityKeyId)
+}
@@ -7,7 +7,7 @@ import (
 	"encoding/pem"
 )
 
-//go:embed *.pem *.der
+//go:embed *.pem *.der *.p12 *.p7b
 var FS embed.FS
 
 // Chain 1
@@ -49,6 +49,45 @@ var (
 	Chain3Cert *x509.Certificate
 )
 
+// Encrypted versions of the chain 1 key, with passphrase EncryptedKeyPassphrase.
+var (
+	//go:embed encrypted-chain-1-pbkdf2.pem
+	EncryptedChain1KeyPBKDF2PEM []byte
+	//go:embed encrypted-chain-1-scrypt.pem
+	EncryptedChain1KeyScryptPEM []byte
+	//go:embed encrypted-chain-1-legacy.pem
+	EncryptedChain1KeyLegacyPEM []byte
+)
+
+const EncryptedKeyPassphrase = "certdeck"
+
+// PKCS#12 bundles of chain 1, with password PKCS12Password. The legacy bundle encrypts the certificates with RC2
+// and the key with 3DES.
+var (
+	//go:embed chain-1-modern.p12
+	Chain1ModernP12 []byte
+	//go:embed chain-1-legacy.p12
+	Chain1LegacyP12 []byte
+)
+
+const PKCS12Password = "certdeck"
+
+// Root certificate authority, that issued chain 3.
+var (
+	//go:embed cacert.pem
+	CACertPEM []byte
+
+	CACert *x509.Certificate
+)
+
+// PKCS#7 certs-only bundles of the full chain, leaf first.
+var (
+	//go:embed chain.p7b
+	ChainP7BPEM []byte
+	//go:embed chain-p7b.der
+	ChainP7BDER []byte
+)
+
 func parseKeyPair(keypairDER []byte, certPEM []byte) (crypto.Signer, *x509.Certificate) {
 	key, err := x509.ParsePKCS8PrivateKey(keypairDER)
 	if err != nil {
@@ -72,4 +111,14 @@ func init() {
 	Chain1Key, Chain1Cert = parseKeyPair(Chain1KeyDER, Chain1CertPEM)
 	Chain2Key, Chain2Cert = parseKeyPair(Chain2KeyDER, Chain2CertPEM)
 	Chain3Key, Chain3Cert = parseKeyPair(Chain3KeyDER, Chain3CertPEM)
+
+	block, _ := pem.Decode(CACertPEM)
+	if block == nil {
+		panic("failed to decode certificate")
+	}
+
+	var err error
+	if CACert, err = x509.ParseCertificate(block.Bytes); err != nil {
+		panic(err)
+	}
 }
@@ -0,0 +1,519 @@
+package certdeck
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+)
+
+var ErrInvalidJWK = errors.New("invalid JWK")
+
+// JWK is a JSON Web Key, as defined in RFC 7517. Key members follow RFC 7518 for RSA and EC keys, and RFC 8037
+// for Ed25519 keys (OKP).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+
+	// EC and OKP members.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	Y     string `json:"y,omitempty"`
+
+	// RSA members.
+	N  string `json:"n,omitempty"`
+	E  string `json:"e,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+
+	// D is the private exponent for RSA keys, and the private key for EC and OKP keys.
+	D string `json:"d,omitempty"`
+
+	// X509Chain holds the standard base64 encoded DER certificates, leaf first.
+	X509Chain []string `json:"x5c,omitempty"`
+	// X509ThumbprintS256 is the base64url encoded SHA-256 hash of the DER leaf certificate.
+	X509ThumbprintS256 string `json:"x5t#S256,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []*JWK `json:"keys"`
+}
+
+const (
+	jwkTypeRSA = "RSA"
+	jwkTypeEC  = "EC"
+	jwkTypeOKP = "OKP"
+
+	jwkCurveEd25519 = "Ed25519"
+)
+
+var jwkCurves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// JWKOptions configures the encoding of a row to a JWK.
+type JWKOptions struct {
+	// IncludePrivate adds the private members of the key to the JWK. Never publish such a JWK.
+	IncludePrivate bool
+
+	// Use is the optional "use" member, like "sig" or "enc".
+	Use string
+	// Algorithm is the optional "alg" member, like "RS256" or "ES256".
+	Algorithm string
+
+	// KeyIDMethod derives the "kid" member from the public key, when the leaf certificate has no subject key
+	// identifier.
+	//
+	// KeyIDSHA1 is used by default.
+	KeyIDMethod KeyIDMethod
+}
+
+func jwkEncode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func jwkEncodeInt(value *big.Int) string {
+	return jwkEncode(value.Bytes())
+}
+
+// jwkEncodeFixed encodes a big integer on a fixed number of bytes, as required for EC coordinates.
+func jwkEncodeFixed(value *big.Int, size int) string {
+	return jwkEncode(value.FillBytes(make([]byte, size)))
+}
+
+func jwkDecode(name, value string) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("%w: missing %q member", ErrInvalidJWK, name)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode %q member: %w", ErrInvalidJWK, name, err)
+	}
+
+	return data, nil
+}
+
+func jwkDecodeInt(name, value string) (*big.Int, error) {
+	data, err := jwkDecode(name, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+func (jwk *JWK) setPublicKey(pub crypto.PublicKey) error {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = jwkTypeRSA
+		jwk.N = jwkEncodeInt(key.N)
+		jwk.E = jwkEncodeInt(big.NewInt(int64(key.E)))
+	case *ecdsa.PublicKey:
+		curveName := key.Curve.Params().Name
+		if _, ok := jwkCurves[curveName]; !ok {
+			return fmt.Errorf("%w: unsupported curve %s", ErrInvalidJWK, curveName)
+		}
+
+		size := (key.Curve.Params().BitSize + 7) / 8
+
+		jwk.KeyType = jwkTypeEC
+		jwk.Curve = curveName
+		jwk.X = jwkEncodeFixed(key.X, size)
+		jwk.Y = jwkEncodeFixed(key.Y, size)
+	case ed25519.PublicKey:
+		jwk.KeyType = jwkTypeOKP
+		jwk.Curve = jwkCurveEd25519
+		jwk.X = jwkEncode(key)
+	default:
+		return fmt.Errorf("%w: unsupported public key %T", ErrInvalidJWK, pub)
+	}
+
+	return nil
+}
+
+func (jwk *JWK) setPrivateKey(priv crypto.Signer) error {
+	switch key := priv.(type) {
+	case *rsa.PrivateKey:
+		if len(key.Primes) != 2 {
+			return fmt.Errorf("%w: multi-prime RSA keys are not supported", ErrInvalidJWK)
+		}
+
+		key.Precompute()
+
+		jwk.D = jwkEncodeInt(key.D)
+		jwk.P = jwkEncodeInt(key.Primes[0])
+		jwk.Q = jwkEncodeInt(key.Primes[1])
+		jwk.DP = jwkEncodeInt(key.Precomputed.Dp)
+		jwk.DQ = jwkEncodeInt(key.Precomputed.Dq)
+		jwk.QI = jwkEncodeInt(key.Precomputed.Qinv)
+	case *ecdsa.PrivateKey:
+		jwk.D = jwkEncodeFixed(key.D, (key.Curve.Params().BitSize+7)/8)
+	case ed25519.PrivateKey:
+		jwk.D = jwkEncode(key.Seed())
+	default:
+		return fmt.Errorf("%w: unsupported private key %T", ErrInvalidJWK, priv)
+	}
+
+	return nil
+}
+
+// RowToJWK encodes the leaf certificate of a row, its chain and optionally its private key, to a JWK.
+//
+// The "kid" member is the subject key identifier of the leaf certificate, or is derived from its public key if
+// the leaf has none. If opts is nil, the default options are used.
+func RowToJWK(row CollectionRow, opts *JWKOptions) (*JWK, error) {
+	if opts == nil {
+		opts = &JWKOptions{}
+	}
+
+	certs := row.Certificates()
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: no certificate", ErrInvalidJWK)
+	}
+
+	leaf := certs[0]
+
+	jwk := &JWK{
+		Use:       opts.Use,
+		Algorithm: opts.Algorithm,
+		X509Chain: CertsToBase64(certs...),
+	}
+
+	if err := jwk.setPublicKey(leaf.PublicKey); err != nil {
+		return nil, err
+	}
+
+	keyID := leaf.SubjectKeyId
+	if len(keyID) == 0 {
+		var err error
+
+		keyID, err = KeyID(leaf.PublicKey, opts.KeyIDMethod)
+		if err != nil {
+			return nil, fmt.Errorf("derive key id: %w", err)
+		}
+	}
+
+	jwk.KeyID = jwkEncode(keyID)
+
+	thumbprint := sha256.Sum256(leaf.Raw)
+	jwk.X509ThumbprintS256 = jwkEncode(thumbprint[:])
+
+	if opts.IncludePrivate {
+		key := row.Key()
+		if key == nil {
+			return nil, fmt.Errorf("%w: no private key", ErrInvalidJWK)
+		}
+
+		if err := MatchKey(key.Public(), certs); err != nil {
+			return nil, err
+		}
+
+		if err := jwk.setPrivateKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return jwk, nil
+}
+
+// RowsToJWKS encodes multiple rows to a JWK set. See RowToJWK.
+func RowsToJWKS(rows []CollectionRow, opts *JWKOptions) (*JWKS, error) {
+	jwks := &JWKS{Keys: make([]*JWK, len(rows))}
+
+	for pos, row := range rows {
+		jwk, err := RowToJWK(row, opts)
+		if err != nil {
+			return nil, fmt.Errorf("encode row %d: %w", pos, err)
+		}
+
+		jwks.Keys[pos] = jwk
+	}
+
+	return jwks, nil
+}
+
+// PublicKey decodes the public key of the JWK.
+func (jwk *JWK) PublicKey() (crypto.PublicKey, error) {
+	switch jwk.KeyType {
+	case jwkTypeRSA:
+		n, err := jwkDecodeInt("n", jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := jwkDecodeInt("e", jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("%w: invalid RSA exponent", ErrInvalidJWK)
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case jwkTypeEC:
+		curve, ok := jwkCurves[jwk.Curve]
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported curve %q", ErrInvalidJWK, jwk.Curve)
+		}
+
+		x, err := jwkDecodeInt("x", jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := jwkDecodeInt("y", jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		key := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+
+		// Conversion to ECDH checks the point is on the curve.
+		if _, err = key.ECDH(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidJWK, err)
+		}
+
+		return key, nil
+	case jwkTypeOKP:
+		if jwk.Curve != jwkCurveEd25519 {
+			return nil, fmt.Errorf("%w: unsupported curve %q", ErrInvalidJWK, jwk.Curve)
+		}
+
+		x, err := jwkDecode("x", jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 public key size %d", ErrInvalidJWK, len(x))
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidJWK, jwk.KeyType)
+	}
+}
+
+// PrivateKey decodes the private key of the JWK. It fails if the JWK has no private members.
+func (jwk *JWK) PrivateKey() (crypto.Signer, error) {
+	if jwk.D == "" {
+		return nil, fmt.Errorf("%w: no private key", ErrInvalidJWK)
+	}
+
+	pub, err := jwk.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		d, err := jwkDecodeInt("d", jwk.D)
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := jwkDecodeInt("p", jwk.P)
+		if err != nil {
+			return nil, err
+		}
+
+		q, err := jwkDecodeInt("q", jwk.Q)
+		if err != nil {
+			return nil, err
+		}
+
+		priv := &rsa.PrivateKey{PublicKey: *key, D: d, Primes: []*big.Int{p, q}}
+		if err = priv.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidJWK, err)
+		}
+
+		priv.Precompute()
+
+		return priv, nil
+	case *ecdsa.PublicKey:
+		d, err := jwkDecodeInt("d", jwk.D)
+		if err != nil {
+			return nil, err
+		}
+
+		priv := &ecdsa.PrivateKey{PublicKey: *key, D: d}
+
+		// Make sure the private key matches the public one.
+		ecdhKey, err := priv.ECDH()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidJWK, err)
+		}
+
+		ecdhPub, err := key.ECDH()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidJWK, err)
+		}
+
+		if !ecdhKey.PublicKey().Equal(ecdhPub) {
+			return nil, fmt.Errorf("%w: private key does not match the public key", ErrInvalidJWK)
+		}
+
+		return priv, nil
+	case ed25519.PublicKey:
+		seed, err := jwkDecode("d", jwk.D)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("%w: invalid Ed25519 private key size %d", ErrInvalidJWK, len(seed))
+		}
+
+		priv := ed25519.NewKeyFromSeed(seed)
+		if !key.Equal(priv.Public()) {
+			return nil, fmt.Errorf("%w: private key does not match the public key", ErrInvalidJWK)
+		}
+
+		return priv, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidJWK, jwk.KeyType)
+	}
+}
+
+// Certificates decodes the "x5c" chain of the JWK. The "x5t#S256" thumbprint, if present, must match the leaf,
+// and the leaf must hold the public key of the JWK.
+func (jwk *JWK) Certificates() ([]*x509.Certificate, error) {
+	if len(jwk.X509Chain) == 0 {
+		return nil, fmt.Errorf("%w: missing \"x5c\" member", ErrInvalidJWK)
+	}
+
+	certs, err := Base64ToCerts(jwk.X509Chain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidJWK, err)
+	}
+
+	if jwk.X509ThumbprintS256 != "" {
+		thumbprint := sha256.Sum256(certs[0].Raw)
+		if jwkEncode(thumbprint[:]) != jwk.X509ThumbprintS256 {
+			return nil, fmt.Errorf("%w: \"x5t#S256\" does not match the leaf certificate", ErrInvalidJWK)
+		}
+	}
+
+	pub, err := jwk.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = MatchKey(pub, certs); err != nil {
+		return nil, err
+	}
+
+	return certs, nil
+}
+
+// JWKToRow decodes a JWK with an "x5c" chain and private members.
+func JWKToRow(jwk *JWK) (*CollectionRowBase, error) {
+	certs, err := jwk.Certificates()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := jwk.PrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	row := &CollectionRowBase{Certs: certs, CertKey: key}
+	if err = row.Fill(); err != nil {
+		return nil, fmt.Errorf("fill row: %w", err)
+	}
+
+	return row, nil
+}
+
+type JWKSHandlerConfig struct {
+	// Collection is used to retrieve the rows.
+	Collection Collection
+	// Providers of the rows to publish, in order.
+	Providers []CertsProvider
+
+	// Options used to encode the rows. IncludePrivate is ignored.
+	Options *JWKOptions
+}
+
+type jwksHandler struct {
+	collection Collection
+	providers  []CertsProvider
+	options    JWKOptions
+}
+
+func (handler *jwksHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	rows := make([]CollectionRow, len(handler.providers))
+
+	for pos, provider := range handler.providers {
+		row, err := handler.collection.GetContext(r.Context(), provider)
+		if err != nil {
+			http.Error(w, "retrieve keys", http.StatusInternalServerError)
+			return
+		}
+
+		rows[pos] = row
+	}
+
+	jwks, err := RowsToJWKS(rows, &handler.options)
+	if err != nil {
+		http.Error(w, "encode keys", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := json.Marshal(jwks)
+	if err != nil {
+		http.Error(w, "encode keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// NewJWKSHandler returns a http.Handler that serves the public JWK set of the rows held in a Collection. Private
+// members are never published.
+func NewJWKSHandler(config *JWKSHandlerConfig) (http.Handler, error) {
+	if config.Collection == nil {
+		return nil, errors.New("jwks handler requires a collection")
+	}
+
+	var options JWKOptions
+	if config.Options != nil {
+		options = *config.Options
+	}
+
+	options.IncludePrivate = false
+
+	return &jwksHandler{
+		collection: config.Collection,
+		providers:  config.Providers,
+		options:    options,
+	}, nil
+}
@@ -0,0 +1,298 @@
+package certdeck_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	"github.com/a-novel-kit/certdeck/internal/certs"
+	certdeckmocks "github.com/a-novel-kit/certdeck/mocks"
+)
+
+func newJWKTestRow(t *testing.T, key crypto.Signer) *certdeck.CollectionRowBase {
+	t.Helper()
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "jwk"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+
+	return &certdeck.CollectionRowBase{Certs: []*x509.Certificate{cert}, CertKey: key}
+}
+
+func TestJWK(t *testing.T) {
+	p256Key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	p521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+
+		row *certdeck.CollectionRowBase
+
+		expectKeyType string
+		expectCurve   string
+	}{
+		{
+			name: "RSA",
+			row: &certdeck.CollectionRowBase{
+				Certs:   []*x509.Certificate{certs.Chain1Cert, certs.Chain2Cert, certs.Chain3Cert},
+				CertKey: certs.Chain1Key,
+			},
+			expectKeyType: "RSA",
+		},
+		{
+			name:          "P-256",
+			row:           newJWKTestRow(t, p256Key),
+			expectKeyType: "EC",
+			expectCurve:   "P-256",
+		},
+		{
+			name:          "P-521",
+			row:           newJWKTestRow(t, p521Key),
+			expectKeyType: "EC",
+			expectCurve:   "P-521",
+		},
+		{
+			name:          "Ed25519",
+			row:           newJWKTestRow(t, ed25519Key),
+			expectKeyType: "OKP",
+			expectCurve:   "Ed25519",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			jwk, err := certdeck.RowToJWK(testCase.row, &certdeck.JWKOptions{IncludePrivate: true, Use: "sig"})
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectKeyType, jwk.KeyType)
+			require.Equal(t, testCase.expectCurve, jwk.Curve)
+			require.Equal(t, "sig", jwk.Use)
+			require.NotEmpty(t, jwk.KeyID)
+			require.NotEmpty(t, jwk.X509ThumbprintS256)
+			require.Len(t, jwk.X509Chain, len(testCase.row.Certs))
+
+			// Go through JSON, as a consumer would.
+			raw, err := json.Marshal(jwk)
+			require.NoError(t, err)
+
+			var decoded certdeck.JWK
+			require.NoError(t, json.Unmarshal(raw, &decoded))
+
+			row, err := certdeck.JWKToRow(&decoded)
+			require.NoError(t, err)
+			require.NoError(t, certdeck.Match(testCase.row.Certs, row.Certificates()))
+			require.True(t, testCase.row.CertKey.(interface{ Equal(crypto.PrivateKey) bool }).Equal(row.Key()))
+
+			// Public JWKs have no private members.
+			public, err := certdeck.RowToJWK(testCase.row, nil)
+			require.NoError(t, err)
+			require.Empty(t, public.D)
+			require.Empty(t, public.P)
+			require.Equal(t, jwk.KeyID, public.KeyID)
+
+			_, err = public.PrivateKey()
+			require.ErrorIs(t, err, certdeck.ErrInvalidJWK)
+
+			pub, err := public.PublicKey()
+			require.NoError(t, err)
+			require.True(t, testCase.row.CertKey.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(pub))
+		})
+	}
+}
+
+func TestJWKKeyID(t *testing.T) {
+	// The subject key identifier of the leaf is used when present.
+	jwk, err := certdeck.RowToJWK(&certdeck.CollectionRowBase{Certs: []*x509.Certificate{certs.Chain1Cert}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(certs.Chain1Cert.SubjectKeyId), jwk.KeyID)
+
+	// Otherwise, it is derived from the public key.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	row := newJWKTestRow(t, key)
+	require.Empty(t, row.Certs[0].SubjectKeyId)
+
+	expected, err := certdeck.KeyID(key.Public(), certdeck.KeyIDSHA256Truncated)
+	require.NoError(t, err)
+
+	jwk, err = certdeck.RowToJWK(row, &certdeck.JWKOptions{KeyIDMethod: certdeck.KeyIDSHA256Truncated})
+	require.NoError(t, err)
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(expected), jwk.KeyID)
+}
+
+func TestJWKInvalid(t *testing.T) {
+	jwk, err := certdeck.RowToJWK(&certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain1Cert, certs.Chain2Cert},
+		CertKey: certs.Chain1Key,
+	}, nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+
+		mutate func(jwk *certdeck.JWK)
+
+		expectErr error
+	}{
+		{
+			name: "WrongThumbprint",
+			mutate: func(jwk *certdeck.JWK) {
+				jwk.X509ThumbprintS256 = "foo"
+			},
+			expectErr: certdeck.ErrInvalidJWK,
+		},
+		{
+			name: "KeyMismatch",
+			mutate: func(jwk *certdeck.JWK) {
+				// Swap the leaf with its issuer.
+				jwk.X509Chain = []string{jwk.X509Chain[1]}
+				jwk.X509ThumbprintS256 = ""
+			},
+			expectErr: certdeck.ErrCertKeyMismatch,
+		},
+		{
+			name: "NoChain",
+			mutate: func(jwk *certdeck.JWK) {
+				jwk.X509Chain = nil
+			},
+			expectErr: certdeck.ErrInvalidJWK,
+		},
+		{
+			name: "UnknownKeyType",
+			mutate: func(jwk *certdeck.JWK) {
+				jwk.KeyType = "foo"
+			},
+			expectErr: certdeck.ErrInvalidJWK,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mutated := *jwk
+			testCase.mutate(&mutated)
+
+			_, err := mutated.Certificates()
+			require.ErrorIs(t, err, testCase.expectErr)
+		})
+	}
+
+	t.Run("PointNotOnCurve", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		ecJWK, err := certdeck.RowToJWK(newJWKTestRow(t, key), nil)
+		require.NoError(t, err)
+
+		ecJWK.Y = ecJWK.X
+
+		_, err = ecJWK.PublicKey()
+		require.ErrorIs(t, err, certdeck.ErrInvalidJWK)
+	})
+}
+
+func TestJWKSHandler(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	ecRow := newJWKTestRow(t, key)
+	rsaRow := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain1Cert},
+		CertKey: certs.Chain1Key,
+	}
+
+	provider1 := certdeckmocks.NewMockCollectionUpdater(t)
+	provider1.On("ID").Return("provider-1")
+	provider1.On("Retrieve").Return(rsaRow, nil).Once()
+
+	provider2 := certdeckmocks.NewMockCollectionUpdater(t)
+	provider2.On("ID").Return("provider-2")
+	provider2.On("Retrieve").Return(ecRow, nil).Once()
+
+	collection := certdeck.NewCollection(time.Hour)
+
+	handler, err := certdeck.NewJWKSHandler(&certdeck.JWKSHandlerConfig{
+		Collection: collection,
+		Providers:  []certdeck.CertsProvider{provider1, provider2},
+		// Private members must never be published.
+		Options: &certdeck.JWKOptions{IncludePrivate: true, Use: "sig"},
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for range 2 {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		var jwks certdeck.JWKS
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&jwks))
+		require.NoError(t, resp.Body.Close())
+
+		require.Len(t, jwks.Keys, 2)
+		require.Equal(t, "RSA", jwks.Keys[0].KeyType)
+		require.Equal(t, "EC", jwks.Keys[1].KeyType)
+
+		for _, jwk := range jwks.Keys {
+			require.Equal(t, "sig", jwk.Use)
+			require.Empty(t, jwk.D)
+
+			_, err = jwk.Certificates()
+			require.NoError(t, err)
+		}
+	}
+
+	t.Run("provider error", func(t *testing.T) {
+		failing := certdeckmocks.NewMockCollectionUpdater(t)
+		failing.On("ID").Return("failing")
+		failing.On("Retrieve").Return(nil, errors.New("uh oh"))
+
+		handler, err := certdeck.NewJWKSHandler(&certdeck.JWKSHandlerConfig{
+			Collection: collection,
+			Providers:  []certdeck.CertsProvider{failing},
+		})
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusInternalServerError, recorder.Code)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", nil))
+		require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	})
+}
@@ -1,10 +1,13 @@
 package certdeck
 
 import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"reflect"
 )
 
 var (
@@ -12,7 +15,7 @@ var (
 	ErrCertKeyMismatch = errors.New("public key mismatch")
 )
 
-// Match checks if two certificate chains are semantically equal.
+// Match checks if two certificate chains are semantically equal. Use DiffChains to describe the differences.
 func Match(chain1, chain2 []*x509.Certificate) error {
 	if len(chain1) != len(chain2) {
 		return ErrCertMismatch
@@ -27,16 +30,82 @@ func Match(chain1, chain2 []*x509.Certificate) error {
 	return nil
 }
 
-// MatchKey checks if the public key matches the certificate.
-func MatchKey(keyPub interface{}, certs []*x509.Certificate) error {
-	if len(certs) == 0 || keyPub == nil {
-		return nil
+// publicKeyEqualer is implemented by the public keys of the standard library.
+type publicKeyEqualer interface {
+	Equal(x crypto.PublicKey) bool
+}
+
+// publicKey returns the public part of a key, that may be a public key or a crypto.Signer. Pointers to Ed25519
+// keys are dereferenced, as the standard library only uses their value form.
+func publicKey(key any) crypto.PublicKey {
+	switch typed := key.(type) {
+	case *ed25519.PublicKey:
+		if typed == nil {
+			return nil
+		}
+
+		return *typed
+	case *ed25519.PrivateKey:
+		if typed == nil {
+			return nil
+		}
+
+		return typed.Public()
+	case crypto.Signer:
+		return typed.Public()
+	default:
+		return key
+	}
+}
+
+// keyFingerprint returns the hex encoded SHA-256 hash of the DER encoded public key, as computed by
+// "openssl pkey -pubout -outform DER | sha256sum".
+func keyFingerprint(pub crypto.PublicKey) string {
+	spkiDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return fmt.Sprintf("unsupported key %T", pub)
 	}
 
-	certPub := certs[0].PublicKey
-	if !reflect.DeepEqual(certPub, keyPub) {
-		return fmt.Errorf("%w:\n\tgot %[2]s (%[2]T)\n\twanted %[3]s (%[3]T)", ErrCertKeyMismatch, certPub, keyPub)
+	sum := sha256.Sum256(spkiDER)
+
+	return "SHA256:" + hex.EncodeToString(sum[:])
+}
+
+// matchPublicKey checks that both public keys are equal, using the Equal method of the standard library keys.
+func matchPublicKey(key any, expected crypto.PublicKey, target string) error {
+	pub := publicKey(key)
+
+	equaler, ok := pub.(publicKeyEqualer)
+	if !ok || !equaler.Equal(publicKey(expected)) {
+		return fmt.Errorf(
+			"%w: key %s does not match %s key %s",
+			ErrCertKeyMismatch, keyFingerprint(pub), target, keyFingerprint(publicKey(expected)),
+		)
 	}
 
 	return nil
 }
+
+// MatchKey checks if a key matches the leaf certificate, which is the first certificate of the chain. The key can
+// either be a public key, or a crypto.Signer like a private key.
+//
+// It does nothing if the key is nil or the chain is empty.
+func MatchKey(key any, certs []*x509.Certificate) error {
+	if len(certs) == 0 || key == nil {
+		return nil
+	}
+
+	return matchPublicKey(key, certs[0].PublicKey, "certificate")
+}
+
+// MatchKeyToCSR checks if a key matches a certificate signing request. The key can either be a public key, or
+// a crypto.Signer like a private key.
+//
+// It does nothing if the key or the request is nil.
+func MatchKeyToCSR(key any, csr *x509.CertificateRequest) error {
+	if csr == nil || key == nil {
+		return nil
+	}
+
+	return matchPublicKey(key, csr.PublicKey, "certificate request")
+}
@@ -1,8 +1,15 @@
 package certdeck_test
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -69,6 +76,23 @@ func TestMatch(t *testing.T) {
 }
 
 func TestMatchKey(t *testing.T) {
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	edTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ed25519"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	edCertRaw, err := x509.CreateCertificate(rand.Reader, edTemplate, edTemplate, edPub, edKey)
+	require.NoError(t, err)
+	edCert, err := x509.ParseCertificate(edCertRaw)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
 	testCases := []struct {
 		name string
 
@@ -101,6 +125,40 @@ func TestMatchKey(t *testing.T) {
 				certs.Chain2Cert,
 			},
 
+			expect: certdeck.ErrCertKeyMismatch,
+		},
+		{
+			name: "private key",
+
+			keyPub: certs.Chain1Key,
+			certs:  []*x509.Certificate{certs.Chain1Cert},
+		},
+		{
+			name: "ed25519 pointer",
+
+			keyPub: &edPub,
+			certs:  []*x509.Certificate{edCert},
+		},
+		{
+			name: "ed25519 private key pointer",
+
+			keyPub: &edKey,
+			certs:  []*x509.Certificate{edCert},
+		},
+		{
+			name: "different key types",
+
+			keyPub: ecKey,
+			certs:  []*x509.Certificate{certs.Chain1Cert},
+
+			expect: certdeck.ErrCertKeyMismatch,
+		},
+		{
+			name: "unsupported key",
+
+			keyPub: "foo",
+			certs:  []*x509.Certificate{certs.Chain1Cert},
+
 			expect: certdeck.ErrCertKeyMismatch,
 		},
 	}
@@ -112,3 +170,30 @@ func TestMatchKey(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchKeyError(t *testing.T) {
+	err := certdeck.MatchKey(certs.Chain3Key, []*x509.Certificate{certs.Chain1Cert})
+	require.ErrorIs(t, err, certdeck.ErrCertKeyMismatch)
+
+	// The error shows fingerprints, not the content of the keys.
+	require.Regexp(
+		t,
+		`^public key mismatch: key SHA256:[0-9a-f]{64} does not match certificate key SHA256:[0-9a-f]{64}$`,
+		err.Error(),
+	)
+}
+
+func TestMatchKeyToCSR(t *testing.T) {
+	csrRaw, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "csr"},
+	}, certs.Chain1Key)
+	require.NoError(t, err)
+
+	csr, err := x509.ParseCertificateRequest(csrRaw)
+	require.NoError(t, err)
+
+	require.NoError(t, certdeck.MatchKeyToCSR(certs.Chain1Key, csr))
+	require.NoError(t, certdeck.MatchKeyToCSR(certs.Chain1Key.Public(), csr))
+	require.ErrorIs(t, certdeck.MatchKeyToCSR(certs.Chain2Key, csr), certdeck.ErrCertKeyMismatch)
+	require.NoError(t, certdeck.MatchKeyToCSR(nil, csr))
+}
@@ -0,0 +1,198 @@
+// Code generated by mockery v2.50.0. DO NOT EDIT.
+
+package certdeckmocks
+
+import (
+	context "context"
+
+	certdeck "github.com/a-novel-kit/certdeck"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockContextCertsProvider is an autogenerated mock type for the ContextCertsProvider type
+type MockContextCertsProvider struct {
+	mock.Mock
+}
+
+type MockContextCertsProvider_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockContextCertsProvider) EXPECT() *MockContextCertsProvider_Expecter {
+	return &MockContextCertsProvider_Expecter{mock: &_m.Mock}
+}
+
+// ID provides a mock function with no fields
+func (_m *MockContextCertsProvider) ID() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ID")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// MockContextCertsProvider_ID_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ID'
+type MockContextCertsProvider_ID_Call struct {
+	*mock.Call
+}
+
+// ID is a helper method to define mock.On call
+func (_e *MockContextCertsProvider_Expecter) ID() *MockContextCertsProvider_ID_Call {
+	return &MockContextCertsProvider_ID_Call{Call: _e.mock.On("ID")}
+}
+
+func (_c *MockContextCertsProvider_ID_Call) Run(run func()) *MockContextCertsProvider_ID_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockContextCertsProvider_ID_Call) Return(_a0 string) *MockContextCertsProvider_ID_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockContextCertsProvider_ID_Call) RunAndReturn(run func() string) *MockContextCertsProvider_ID_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Retrieve provides a mock function with no fields
+func (_m *MockContextCertsProvider) Retrieve() (certdeck.CollectionRow, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Retrieve")
+	}
+
+	var r0 certdeck.CollectionRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (certdeck.CollectionRow, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() certdeck.CollectionRow); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(certdeck.CollectionRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockContextCertsProvider_Retrieve_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Retrieve'
+type MockContextCertsProvider_Retrieve_Call struct {
+	*mock.Call
+}
+
+// Retrieve is a helper method to define mock.On call
+func (_e *MockContextCertsProvider_Expecter) Retrieve() *MockContextCertsProvider_Retrieve_Call {
+	return &MockContextCertsProvider_Retrieve_Call{Call: _e.mock.On("Retrieve")}
+}
+
+func (_c *MockContextCertsProvider_Retrieve_Call) Run(run func()) *MockContextCertsProvider_Retrieve_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockContextCertsProvider_Retrieve_Call) Return(_a0 certdeck.CollectionRow, _a1 error) *MockContextCertsProvider_Retrieve_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockContextCertsProvider_Retrieve_Call) RunAndReturn(run func() (certdeck.CollectionRow, error)) *MockContextCertsProvider_Retrieve_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RetrieveContext provides a mock function with given fields: ctx
+func (_m *MockContextCertsProvider) RetrieveContext(ctx context.Context) (certdeck.CollectionRow, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RetrieveContext")
+	}
+
+	var r0 certdeck.CollectionRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (certdeck.CollectionRow, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) certdeck.CollectionRow); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(certdeck.CollectionRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockContextCertsProvider_RetrieveContext_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RetrieveContext'
+type MockContextCertsProvider_RetrieveContext_Call struct {
+	*mock.Call
+}
+
+// RetrieveContext is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockContextCertsProvider_Expecter) RetrieveContext(ctx interface{}) *MockContextCertsProvider_RetrieveContext_Call {
+	return &MockContextCertsProvider_RetrieveContext_Call{Call: _e.mock.On("RetrieveContext", ctx)}
+}
+
+func (_c *MockContextCertsProvider_RetrieveContext_Call) Run(run func(ctx context.Context)) *MockContextCertsProvider_RetrieveContext_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockContextCertsProvider_RetrieveContext_Call) Return(_a0 certdeck.CollectionRow, _a1 error) *MockContextCertsProvider_RetrieveContext_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockContextCertsProvider_RetrieveContext_Call) RunAndReturn(run func(context.Context) (certdeck.CollectionRow, error)) *MockContextCertsProvider_RetrieveContext_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockContextCertsProvider creates a new instance of MockContextCertsProvider. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockContextCertsProvider(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockContextCertsProvider {
+	mock := &MockContextCertsProvider{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
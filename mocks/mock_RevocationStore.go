@@ -0,0 +1,261 @@
+// Code generated by mockery v2.50.0. DO NOT EDIT.
+
+package certdeckmocks
+
+import (
+	context "context"
+	big "math/big"
+
+	certdeck "github.com/a-novel-kit/certdeck"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockRevocationStore is an autogenerated mock type for the RevocationStore type
+type MockRevocationStore struct {
+	mock.Mock
+}
+
+type MockRevocationStore_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockRevocationStore) EXPECT() *MockRevocationStore_Expecter {
+	return &MockRevocationStore_Expecter{mock: &_m.Mock}
+}
+
+// Get provides a mock function with given fields: ctx, serial
+func (_m *MockRevocationStore) Get(ctx context.Context, serial *big.Int) (*certdeck.Revocation, error) {
+	ret := _m.Called(ctx, serial)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Get")
+	}
+
+	var r0 *certdeck.Revocation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *big.Int) (*certdeck.Revocation, error)); ok {
+		return rf(ctx, serial)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *big.Int) *certdeck.Revocation); ok {
+		r0 = rf(ctx, serial)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*certdeck.Revocation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *big.Int) error); ok {
+		r1 = rf(ctx, serial)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRevocationStore_Get_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Get'
+type MockRevocationStore_Get_Call struct {
+	*mock.Call
+}
+
+// Get is a helper method to define mock.On call
+//   - ctx context.Context
+//   - serial *big.Int
+func (_e *MockRevocationStore_Expecter) Get(ctx interface{}, serial interface{}) *MockRevocationStore_Get_Call {
+	return &MockRevocationStore_Get_Call{Call: _e.mock.On("Get", ctx, serial)}
+}
+
+func (_c *MockRevocationStore_Get_Call) Run(run func(ctx context.Context, serial *big.Int)) *MockRevocationStore_Get_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*big.Int))
+	})
+	return _c
+}
+
+func (_c *MockRevocationStore_Get_Call) Return(_a0 *certdeck.Revocation, _a1 error) *MockRevocationStore_Get_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRevocationStore_Get_Call) RunAndReturn(run func(context.Context, *big.Int) (*certdeck.Revocation, error)) *MockRevocationStore_Get_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// List provides a mock function with given fields: ctx
+func (_m *MockRevocationStore) List(ctx context.Context) ([]*certdeck.Revocation, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for List")
+	}
+
+	var r0 []*certdeck.Revocation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*certdeck.Revocation, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*certdeck.Revocation); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*certdeck.Revocation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRevocationStore_List_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'List'
+type MockRevocationStore_List_Call struct {
+	*mock.Call
+}
+
+// List is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockRevocationStore_Expecter) List(ctx interface{}) *MockRevocationStore_List_Call {
+	return &MockRevocationStore_List_Call{Call: _e.mock.On("List", ctx)}
+}
+
+func (_c *MockRevocationStore_List_Call) Run(run func(ctx context.Context)) *MockRevocationStore_List_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockRevocationStore_List_Call) Return(_a0 []*certdeck.Revocation, _a1 error) *MockRevocationStore_List_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRevocationStore_List_Call) RunAndReturn(run func(context.Context) ([]*certdeck.Revocation, error)) *MockRevocationStore_List_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NextCRLNumber provides a mock function with given fields: ctx
+func (_m *MockRevocationStore) NextCRLNumber(ctx context.Context) (*big.Int, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NextCRLNumber")
+	}
+
+	var r0 *big.Int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*big.Int, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *big.Int); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*big.Int)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockRevocationStore_NextCRLNumber_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NextCRLNumber'
+type MockRevocationStore_NextCRLNumber_Call struct {
+	*mock.Call
+}
+
+// NextCRLNumber is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockRevocationStore_Expecter) NextCRLNumber(ctx interface{}) *MockRevocationStore_NextCRLNumber_Call {
+	return &MockRevocationStore_NextCRLNumber_Call{Call: _e.mock.On("NextCRLNumber", ctx)}
+}
+
+func (_c *MockRevocationStore_NextCRLNumber_Call) Run(run func(ctx context.Context)) *MockRevocationStore_NextCRLNumber_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockRevocationStore_NextCRLNumber_Call) Return(_a0 *big.Int, _a1 error) *MockRevocationStore_NextCRLNumber_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockRevocationStore_NextCRLNumber_Call) RunAndReturn(run func(context.Context) (*big.Int, error)) *MockRevocationStore_NextCRLNumber_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Revoke provides a mock function with given fields: ctx, revocation
+func (_m *MockRevocationStore) Revoke(ctx context.Context, revocation *certdeck.Revocation) error {
+	ret := _m.Called(ctx, revocation)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revoke")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *certdeck.Revocation) error); ok {
+		r0 = rf(ctx, revocation)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockRevocationStore_Revoke_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Revoke'
+type MockRevocationStore_Revoke_Call struct {
+	*mock.Call
+}
+
+// Revoke is a helper method to define mock.On call
+//   - ctx context.Context
+//   - revocation *certdeck.Revocation
+func (_e *MockRevocationStore_Expecter) Revoke(ctx interface{}, revocation interface{}) *MockRevocationStore_Revoke_Call {
+	return &MockRevocationStore_Revoke_Call{Call: _e.mock.On("Revoke", ctx, revocation)}
+}
+
+func (_c *MockRevocationStore_Revoke_Call) Run(run func(ctx context.Context, revocation *certdeck.Revocation)) *MockRevocationStore_Revoke_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*certdeck.Revocation))
+	})
+	return _c
+}
+
+func (_c *MockRevocationStore_Revoke_Call) Return(_a0 error) *MockRevocationStore_Revoke_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockRevocationStore_Revoke_Call) RunAndReturn(run func(context.Context, *certdeck.Revocation) error) *MockRevocationStore_Revoke_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockRevocationStore creates a new instance of MockRevocationStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockRevocationStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockRevocationStore {
+	mock := &MockRevocationStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -122,6 +122,124 @@ func (_c *MockSigner_Sign_Call) RunAndReturn(run func(context.Context, interface
 	return _c
 }
 
+// SignCRL provides a mock function with given fields: ctx
+func (_m *MockSigner) SignCRL(ctx context.Context) (*x509.RevocationList, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SignCRL")
+	}
+
+	var r0 *x509.RevocationList
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*x509.RevocationList, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *x509.RevocationList); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*x509.RevocationList)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSigner_SignCRL_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SignCRL'
+type MockSigner_SignCRL_Call struct {
+	*mock.Call
+}
+
+// SignCRL is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockSigner_Expecter) SignCRL(ctx interface{}) *MockSigner_SignCRL_Call {
+	return &MockSigner_SignCRL_Call{Call: _e.mock.On("SignCRL", ctx)}
+}
+
+func (_c *MockSigner_SignCRL_Call) Run(run func(ctx context.Context)) *MockSigner_SignCRL_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *MockSigner_SignCRL_Call) Return(_a0 *x509.RevocationList, _a1 error) *MockSigner_SignCRL_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSigner_SignCRL_Call) RunAndReturn(run func(context.Context) (*x509.RevocationList, error)) *MockSigner_SignCRL_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SignCSR provides a mock function with given fields: ctx, csr, policy
+func (_m *MockSigner) SignCSR(ctx context.Context, csr *x509.CertificateRequest, policy *certdeck.CSRPolicy) (*x509.Certificate, error) {
+	ret := _m.Called(ctx, csr, policy)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SignCSR")
+	}
+
+	var r0 *x509.Certificate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *x509.CertificateRequest, *certdeck.CSRPolicy) (*x509.Certificate, error)); ok {
+		return rf(ctx, csr, policy)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *x509.CertificateRequest, *certdeck.CSRPolicy) *x509.Certificate); ok {
+		r0 = rf(ctx, csr, policy)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*x509.Certificate)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *x509.CertificateRequest, *certdeck.CSRPolicy) error); ok {
+		r1 = rf(ctx, csr, policy)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSigner_SignCSR_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SignCSR'
+type MockSigner_SignCSR_Call struct {
+	*mock.Call
+}
+
+// SignCSR is a helper method to define mock.On call
+//   - ctx context.Context
+//   - csr *x509.CertificateRequest
+//   - policy *certdeck.CSRPolicy
+func (_e *MockSigner_Expecter) SignCSR(ctx interface{}, csr interface{}, policy interface{}) *MockSigner_SignCSR_Call {
+	return &MockSigner_SignCSR_Call{Call: _e.mock.On("SignCSR", ctx, csr, policy)}
+}
+
+func (_c *MockSigner_SignCSR_Call) Run(run func(ctx context.Context, csr *x509.CertificateRequest, policy *certdeck.CSRPolicy)) *MockSigner_SignCSR_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*x509.CertificateRequest), args[2].(*certdeck.CSRPolicy))
+	})
+	return _c
+}
+
+func (_c *MockSigner_SignCSR_Call) Return(_a0 *x509.Certificate, _a1 error) *MockSigner_SignCSR_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSigner_SignCSR_Call) RunAndReturn(run func(context.Context, *x509.CertificateRequest, *certdeck.CSRPolicy) (*x509.Certificate, error)) *MockSigner_SignCSR_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockSigner creates a new instance of MockSigner. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockSigner(t interface {
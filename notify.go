@@ -0,0 +1,131 @@
+package certdeck
+
+import "crypto"
+
+// CollectionEvent is delivered to the subscribers of a row, when it changes or fails to be fetched.
+type CollectionEvent struct {
+	// ID of the provider of the row.
+	ID string
+	// Old is the row that was cached before the fetch. It is nil for the first fetch of a row.
+	Old CollectionRow
+	// New is the fetched row. It is nil when the fetch failed.
+	New CollectionRow
+	// Diff describes how the certificate chain changed. It is nil when the fetch failed.
+	Diff *ChainDiff
+	// KeyChanged is set when the private key of the row changed.
+	KeyChanged bool
+	// Err is the error returned by the provider. Fetches given up by their caller are not reported.
+	Err error
+}
+
+type subscriber struct {
+	callback func(event *CollectionEvent)
+}
+
+// keysEqual checks whether both private keys have the same public key.
+func keysEqual(a, b crypto.Signer) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	equaler, ok := publicKey(a).(publicKeyEqualer)
+
+	return ok && equaler.Equal(publicKey(b))
+}
+
+// changeEvent returns the event for a fetched row, or nil if neither its chain nor its key changed.
+func changeEvent(name string, old, row CollectionRow) *CollectionEvent {
+	event := &CollectionEvent{ID: name, Old: old, New: row}
+
+	if old == nil {
+		event.Diff = DiffChains(nil, row.Certificates())
+		event.KeyChanged = row.Key() != nil
+
+		return event
+	}
+
+	if Match(old.Certificates(), row.Certificates()) != nil {
+		event.Diff = DiffChains(old.Certificates(), row.Certificates())
+	} else {
+		event.Diff = &ChainDiff{Diffs: []*CertDiff{}}
+	}
+
+	event.KeyChanged = !keysEqual(old.Key(), row.Key())
+
+	if event.Diff.Empty() && !event.KeyChanged {
+		return nil
+	}
+
+	return event
+}
+
+// notify queues an event for the subscribers of its row. Events are delivered in order, by a separate goroutine,
+// so callbacks neither block the fetch nor the callers waiting for it.
+func (collection *collectionImpl) notify(event *CollectionEvent) {
+	if event == nil {
+		return
+	}
+
+	collection.eventsMu.Lock()
+	defer collection.eventsMu.Unlock()
+
+	collection.events = append(collection.events, event)
+
+	if !collection.dispatching {
+		collection.dispatching = true
+
+		go collection.dispatch()
+	}
+}
+
+// dispatch delivers the queued events, until the queue is empty.
+func (collection *collectionImpl) dispatch() {
+	for {
+		collection.eventsMu.Lock()
+
+		if len(collection.events) == 0 {
+			collection.dispatching = false
+			collection.eventsMu.Unlock()
+
+			return
+		}
+
+		event := collection.events[0]
+		collection.events = collection.events[1:]
+		collection.eventsMu.Unlock()
+
+		collection.RLock()
+		subscribers := collection.subscribers[event.ID]
+		collection.RUnlock()
+
+		for _, current := range subscribers {
+			current.callback(event)
+		}
+	}
+}
+
+func (collection *collectionImpl) Subscribe(id string, callback func(event *CollectionEvent)) func() {
+	current := &subscriber{callback: callback}
+
+	collection.Lock()
+	collection.subscribers[id] = append(collection.subscribers[id], current)
+	collection.Unlock()
+
+	return func() {
+		collection.Lock()
+		defer collection.Unlock()
+
+		kept := make([]*subscriber, 0, len(collection.subscribers[id]))
+		for _, other := range collection.subscribers[id] {
+			if other != current {
+				kept = append(kept, other)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(collection.subscribers, id)
+		} else {
+			collection.subscribers[id] = kept
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package certdeck_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	"github.com/a-novel-kit/certdeck/clocktest"
+	"github.com/a-novel-kit/certdeck/internal/certs"
+)
+
+func waitForEvent(t *testing.T, events <-chan *certdeck.CollectionEvent) *certdeck.CollectionEvent {
+	t.Helper()
+
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+
+		return nil
+	}
+}
+
+func TestCollectionSubscribe(t *testing.T) {
+	testRow1 := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain1Cert, certs.Chain2Cert},
+		CertKey: certs.Chain1Key,
+	}
+
+	// Same chain as testRow1, in a new row.
+	testRow1Copy := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain1Cert, certs.Chain2Cert},
+		CertKey: certs.Chain1Key,
+	}
+
+	testRow2 := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain1Cert, certs.Chain3Cert},
+		CertKey: certs.Chain1Key,
+	}
+
+	// Same chain as testRow2, with another key.
+	testRow3 := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain1Cert, certs.Chain3Cert},
+		CertKey: certs.Chain2Key,
+	}
+
+	t.Run("changes", func(t *testing.T) {
+		errFoo := errors.New("foo")
+
+		provider, _ := newRefreshTestProvider(
+			"test-updater",
+			[]certdeck.CollectionRow{testRow1, testRow1Copy, testRow2, testRow3, nil},
+			[]error{nil, nil, nil, nil, errFoo},
+		)
+
+		clock := clocktest.New(time.Now())
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration: time.Minute,
+			Clock:         clock,
+		})
+
+		events := make(chan *certdeck.CollectionEvent, 10)
+
+		unsubscribe := collection.Subscribe(provider.ID(), func(event *certdeck.CollectionEvent) {
+			events <- event
+		})
+
+		// First fetch.
+		_, err := collection.Get(provider)
+		require.NoError(t, err)
+
+		event := waitForEvent(t, events)
+		require.Equal(t, "test-updater", event.ID)
+		require.Nil(t, event.Old)
+		require.Equal(t, testRow1, event.New)
+		require.Len(t, event.Diff.Diffs, 2)
+		require.True(t, event.KeyChanged)
+
+		// Refetched, but nothing changed.
+		clock.Advance(time.Minute)
+
+		_, err = collection.Get(provider)
+		require.NoError(t, err)
+
+		// The chain changed. Events are ordered, so the previous fetch did not send any.
+		clock.Advance(time.Minute)
+
+		_, err = collection.Get(provider)
+		require.NoError(t, err)
+
+		event = waitForEvent(t, events)
+		require.Equal(t, testRow1Copy, event.Old)
+		require.Equal(t, testRow2, event.New)
+		require.Len(t, event.Diff.Diffs, 1)
+		require.Equal(t, 1, event.Diff.Diffs[0].Position)
+		require.Equal(t, certdeck.CertDiffChanged, event.Diff.Diffs[0].Kind)
+		require.False(t, event.KeyChanged)
+
+		// Only the key changed.
+		clock.Advance(time.Minute)
+
+		_, err = collection.Get(provider)
+		require.NoError(t, err)
+
+		event = waitForEvent(t, events)
+		require.True(t, event.Diff.Empty())
+		require.True(t, event.KeyChanged)
+
+		// Errors are reported.
+		clock.Advance(time.Minute)
+
+		_, err = collection.Get(provider)
+		require.ErrorIs(t, err, errFoo)
+
+		event = waitForEvent(t, events)
+		require.Equal(t, testRow3, event.Old)
+		require.Nil(t, event.New)
+		require.ErrorIs(t, event.Err, errFoo)
+
+		unsubscribe()
+
+		clock.Advance(time.Minute)
+
+		_, err = collection.Get(provider)
+		require.ErrorIs(t, err, errFoo)
+		require.Never(t, func() bool { return len(events) > 0 }, 50*time.Millisecond, 5*time.Millisecond)
+	})
+
+	t.Run("callback uses the collection", func(t *testing.T) {
+		errFoo := errors.New("foo")
+
+		provider, calls := newRefreshTestProvider(
+			"test-updater", []certdeck.CollectionRow{nil, testRow1}, []error{errFoo, nil},
+		)
+
+		collection := certdeck.NewCollection(time.Minute)
+
+		type result struct {
+			data certdeck.CollectionRow
+			err  error
+		}
+
+		results := make(chan result, 10)
+
+		collection.Subscribe(provider.ID(), func(event *certdeck.CollectionEvent) {
+			if event.Err == nil {
+				return
+			}
+
+			// The row is not cached, so it is fetched again.
+			data, err := collection.Get(provider)
+			results <- result{data: data, err: err}
+		})
+
+		_, err := collection.Get(provider)
+		require.ErrorIs(t, err, errFoo)
+
+		select {
+		case res := <-results:
+			require.NoError(t, res.err)
+			require.Equal(t, testRow1, res.data)
+		case <-time.After(time.Second):
+			t.Fatal("callback is blocked")
+		}
+
+		require.Len(t, calls, 2)
+	})
+
+	t.Run("other rows", func(t *testing.T) {
+		provider1, _ := newRefreshTestProvider("test-updater-1", []certdeck.CollectionRow{testRow1}, []error{nil})
+		provider2, _ := newRefreshTestProvider("test-updater-2", []certdeck.CollectionRow{testRow2}, []error{nil})
+
+		collection := certdeck.NewCollection(time.Minute)
+
+		events := make(chan *certdeck.CollectionEvent, 10)
+
+		collection.Subscribe(provider1.ID(), func(event *certdeck.CollectionEvent) {
+			events <- event
+		})
+
+		_, err := collection.Get(provider2)
+		require.NoError(t, err)
+
+		_, err = collection.Get(provider1)
+		require.NoError(t, err)
+
+		// Events of provider2 are not delivered.
+		event := waitForEvent(t, events)
+		require.Equal(t, "test-updater-1", event.ID)
+		require.Equal(t, testRow1, event.New)
+	})
+
+	t.Run("abandoned fetch", func(t *testing.T) {
+		provider := certdeck.NewCertsProviderFunc("test-updater", func(ctx context.Context) (certdeck.CollectionRow, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+		collection := certdeck.NewCollection(time.Minute)
+
+		events := make(chan *certdeck.CollectionEvent, 10)
+
+		collection.Subscribe(provider.ID(), func(event *certdeck.CollectionEvent) {
+			events <- event
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// The caller gave up, the provider did not fail.
+		_, err := collection.GetContext(ctx, provider)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Never(t, func() bool { return len(events) > 0 }, 50*time.Millisecond, 5*time.Millisecond)
+	})
+
+	t.Run("background refresh", func(t *testing.T) {
+		errFoo := errors.New("foo")
+
+		clock := clocktest.New(certs.Chain1Cert.NotBefore.Add(time.Hour))
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration:   time.Hour,
+			RefreshInterval: 10 * time.Minute,
+			RefreshJitter:   -1,
+			Clock:           clock,
+		})
+		defer collection.Close()
+
+		provider, _ := newRefreshTestProvider(
+			"test-updater", []certdeck.CollectionRow{testRow1, nil}, []error{nil, errFoo},
+		)
+
+		events := make(chan *certdeck.CollectionEvent, 10)
+
+		collection.Subscribe(provider.ID(), func(event *certdeck.CollectionEvent) {
+			events <- event
+		})
+
+		require.NoError(t, collection.Register(provider))
+
+		event := waitForEvent(t, events)
+		require.Equal(t, testRow1, event.New)
+		waitForRefresh(t, clock)
+
+		// Failed background refreshes are reported.
+		clock.Advance(10 * time.Minute)
+
+		event = waitForEvent(t, events)
+		require.ErrorIs(t, event.Err, errFoo)
+		require.Equal(t, testRow1, event.Old)
+	})
+}
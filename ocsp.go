@@ -0,0 +1,341 @@
+package certdeck
+
+import (
+	"bytes"
+	"container/list"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	"golang.org/x/crypto/ocsp"
+)
+
+// DefaultOCSPResponseValidity is the default interval between the thisUpdate and nextUpdate fields of an OCSP
+// response.
+const DefaultOCSPResponseValidity = time.Hour
+
+var ErrInvalidOCSPResponder = errors.New("invalid OCSP responder certificate")
+
+// DefaultOCSPMaxCachedResponses is the default number of signed responses kept in the cache of the OCSP handler.
+const DefaultOCSPMaxCachedResponses = 10_000
+
+// OCSPMaxRequestSize is the maximum size of a POST request body accepted by the OCSP handler.
+const OCSPMaxRequestSize = 10 * 1024
+
+const (
+	ocspRequestContentType  = "application/ocsp-request"
+	ocspResponseContentType = "application/ocsp-response"
+)
+
+type OCSPHandlerConfig struct {
+	// Issuer is the certificate of the CA that issued the certificates checked by the handler.
+	Issuer *x509.Certificate
+	// ResponderCert is an optional delegated OCSP signing certificate. It must be issued by Issuer, and have
+	// the OCSPSigning extended key usage, otherwise NewOCSPHandler fails with ErrInvalidOCSPResponder.
+	//
+	// If nil, responses are signed by the Issuer directly.
+	ResponderCert *x509.Certificate
+	// ResponderKey signs the responses. It must be the private key of ResponderCert if set, or of the Issuer
+	// otherwise.
+	ResponderKey crypto.Signer
+
+	// RevocationStore is used to look up the status of the requested serial numbers. Serials absent from the
+	// store are reported as good.
+	RevocationStore RevocationStore
+
+	// ResponseValidity is the interval between the thisUpdate and nextUpdate fields of the responses. Signed
+	// responses are cached for this duration.
+	//
+	// DefaultOCSPResponseValidity is used by default.
+	ResponseValidity time.Duration
+	// MaxCachedResponses is the maximum number of signed responses kept in the cache. Once reached, the least
+	// recently used response is evicted, so requests for random serials cannot exhaust the memory.
+	//
+	// DefaultOCSPMaxCachedResponses is used by default.
+	MaxCachedResponses int
+
+	// Clock provides the current time.
+	//
+	// SystemClock is used by default.
+	Clock Clock
+}
+
+type ocspCachedResponse struct {
+	key string
+
+	raw        []byte
+	thisUpdate time.Time
+	nextUpdate time.Time
+}
+
+type ocspHandler struct {
+	issuer        *x509.Certificate
+	responderCert *x509.Certificate
+	responderKey  crypto.Signer
+
+	revocationStore RevocationStore
+
+	responseValidity time.Duration
+
+	clock Clock
+
+	// issuerKeyHashes and issuerNameHashes cache the hashes of the issuer public key and name, for each supported
+	// hash algorithm.
+	issuerKeyHashes  map[crypto.Hash][]byte
+	issuerNameHashes map[crypto.Hash][]byte
+
+	// cache holds the elements of lru, by request. The front of lru is the most recently used response.
+	cache              map[string]*list.Element
+	lru                *list.List
+	maxCachedResponses int
+	mu                 sync.Mutex
+}
+
+var ocspSupportedHashes = []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA384, crypto.SHA512}
+
+func (handler *ocspHandler) writeError(w http.ResponseWriter, response []byte) {
+	w.Header().Set("Content-Type", ocspResponseContentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(response)
+}
+
+func (handler *ocspHandler) readRequest(r *http.Request) ([]byte, error) {
+	switch r.Method {
+	case http.MethodGet:
+		// The request is base64 encoded in the path. It may contain slashes, so the whole path is used.
+		encoded := strings.TrimPrefix(r.URL.Path, "/")
+
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("decode request: %w", err)
+		}
+
+		return raw, nil
+	case http.MethodPost:
+		if contentType := r.Header.Get("Content-Type"); contentType != ocspRequestContentType {
+			return nil, fmt.Errorf("unexpected content type %q", contentType)
+		}
+
+		raw, err := io.ReadAll(io.LimitReader(r.Body, OCSPMaxRequestSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("read request: %w", err)
+		}
+
+		if len(raw) > OCSPMaxRequestSize {
+			return nil, errors.New("request too large")
+		}
+
+		return raw, nil
+	default:
+		return nil, errors.New("unexpected method")
+	}
+}
+
+func (handler *ocspHandler) getCached(key string, now time.Time) *ocspCachedResponse {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+
+	element, ok := handler.cache[key]
+	if !ok {
+		return nil
+	}
+
+	cached := element.Value.(*ocspCachedResponse)
+	if !now.Before(cached.nextUpdate) {
+		return nil
+	}
+
+	handler.lru.MoveToFront(element)
+
+	return cached
+}
+
+// remove deletes a response from the cache. It must be called with the lock held.
+func (handler *ocspHandler) remove(element *list.Element) {
+	handler.lru.Remove(element)
+	delete(handler.cache, element.Value.(*ocspCachedResponse).key)
+}
+
+// insert caches a response. When the cache is full, expired responses are purged first, then the least recently
+// used ones are evicted.
+func (handler *ocspHandler) insert(response *ocspCachedResponse, now time.Time) {
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+
+	if element, ok := handler.cache[response.key]; ok {
+		handler.remove(element)
+	}
+
+	if len(handler.cache) >= handler.maxCachedResponses {
+		for element := handler.lru.Front(); element != nil; {
+			next := element.Next()
+
+			if !now.Before(element.Value.(*ocspCachedResponse).nextUpdate) {
+				handler.remove(element)
+			}
+
+			element = next
+		}
+	}
+
+	for len(handler.cache) > 0 && len(handler.cache) >= handler.maxCachedResponses {
+		handler.remove(handler.lru.Back())
+	}
+
+	handler.cache[response.key] = handler.lru.PushFront(response)
+}
+
+func (handler *ocspHandler) sign(r *http.Request, serial *big.Int, hash crypto.Hash) (*ocspCachedResponse, error) {
+	now := handler.clock.Now()
+
+	template := ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: serial,
+		IssuerHash:   hash,
+		ThisUpdate:   now,
+		NextUpdate:   now.Add(lo.CoalesceOrEmpty(handler.responseValidity, DefaultOCSPResponseValidity)),
+		Certificate:  handler.responderCert,
+	}
+
+	revocation, err := handler.revocationStore.Get(r.Context(), serial)
+	switch {
+	case err == nil:
+		template.Status = ocsp.Revoked
+		template.RevokedAt = revocation.RevokedAt
+		template.RevocationReason = int(revocation.Reason)
+	case !errors.Is(err, ErrNotRevoked):
+		return nil, fmt.Errorf("get revocation: %w", err)
+	}
+
+	responder := lo.CoalesceOrEmpty(handler.responderCert, handler.issuer)
+
+	raw, err := ocsp.CreateResponse(handler.issuer, responder, template, handler.responderKey)
+	if err != nil {
+		return nil, fmt.Errorf("create response: %w", err)
+	}
+
+	return &ocspCachedResponse{raw: raw, thisUpdate: template.ThisUpdate, nextUpdate: template.NextUpdate}, nil
+}
+
+func (handler *ocspHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	rawRequest, err := handler.readRequest(r)
+	if err != nil {
+		handler.writeError(w, ocsp.MalformedRequestErrorResponse)
+		return
+	}
+
+	request, err := ocsp.ParseRequest(rawRequest)
+	if err != nil {
+		handler.writeError(w, ocsp.MalformedRequestErrorResponse)
+		return
+	}
+
+	// Only answer for certificates issued by our issuer.
+	issuerKeyHash, ok := handler.issuerKeyHashes[request.HashAlgorithm]
+	if !ok || !bytes.Equal(issuerKeyHash, request.IssuerKeyHash) ||
+		!bytes.Equal(handler.issuerNameHashes[request.HashAlgorithm], request.IssuerNameHash) {
+		handler.writeError(w, ocsp.UnauthorizedErrorResponse)
+		return
+	}
+
+	now := handler.clock.Now()
+	cacheKey := request.HashAlgorithm.String() + ":" + request.SerialNumber.String()
+
+	response := handler.getCached(cacheKey, now)
+	if response == nil {
+		response, err = handler.sign(r, request.SerialNumber, request.HashAlgorithm)
+		if err != nil {
+			handler.writeError(w, ocsp.InternalErrorErrorResponse)
+			return
+		}
+
+		response.key = cacheKey
+		handler.insert(response, now)
+	}
+
+	w.Header().Set("Content-Type", ocspResponseContentType)
+	w.Header().Set("Last-Modified", response.thisUpdate.UTC().Format(http.TimeFormat))
+	w.Header().Set("Expires", response.nextUpdate.UTC().Format(http.TimeFormat))
+	w.Header().Set(
+		"Cache-Control",
+		"max-age="+strconv.Itoa(int(response.nextUpdate.Sub(now).Seconds()))+", public, no-transform, must-revalidate",
+	)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(response.raw)
+}
+
+// NewOCSPHandler returns a http.Handler that answers OCSP requests (RFC 6960), for certificates issued by the
+// configured issuer.
+//
+// Both GET and POST requests are supported. For GET requests, the whole path of the request is read as the
+// base64 encoded OCSP request, so the handler should be mounted with http.StripPrefix if it does not live at
+// the root.
+func NewOCSPHandler(config *OCSPHandlerConfig) (http.Handler, error) {
+	if config.Issuer == nil || config.ResponderKey == nil || config.RevocationStore == nil {
+		return nil, errors.New("ocsp handler requires an issuer, a responder key and a revocation store")
+	}
+
+	// Clients reject responses signed by a delegated certificate, unless it is issued by the CA, for OCSP signing.
+	if config.ResponderCert != nil {
+		if !isIssuer(config.ResponderCert, config.Issuer) {
+			return nil, fmt.Errorf("%w: not issued by the issuer", ErrInvalidOCSPResponder)
+		}
+
+		if !lo.Contains(config.ResponderCert.ExtKeyUsage, x509.ExtKeyUsageOCSPSigning) {
+			return nil, fmt.Errorf("%w: missing the OCSPSigning extended key usage", ErrInvalidOCSPResponder)
+		}
+	}
+
+	keyBits, err := subjectPublicKeyBits(config.Issuer.RawSubjectPublicKeyInfo)
+	if err != nil {
+		return nil, fmt.Errorf("issuer public key: %w", err)
+	}
+
+	issuerKeyHashes := make(map[crypto.Hash][]byte, len(ocspSupportedHashes))
+	issuerNameHashes := make(map[crypto.Hash][]byte, len(ocspSupportedHashes))
+
+	for _, hash := range ocspSupportedHashes {
+		hasher := hash.New()
+		hasher.Write(keyBits)
+		issuerKeyHashes[hash] = hasher.Sum(nil)
+
+		hasher = hash.New()
+		hasher.Write(config.Issuer.RawSubject)
+		issuerNameHashes[hash] = hasher.Sum(nil)
+	}
+
+	return &ocspHandler{
+		issuer:        config.Issuer,
+		responderCert: config.ResponderCert,
+		responderKey:  config.ResponderKey,
+
+		revocationStore: config.RevocationStore,
+
+		responseValidity: config.ResponseValidity,
+
+		clock: lo.CoalesceOrEmpty(config.Clock, SystemClock),
+
+		issuerKeyHashes:  issuerKeyHashes,
+		issuerNameHashes: issuerNameHashes,
+
+		cache:              make(map[string]*list.Element),
+		lru:                list.New(),
+		maxCachedResponses: lo.CoalesceOrEmpty(config.MaxCachedResponses, DefaultOCSPMaxCachedResponses),
+	}, nil
+}
@@ -0,0 +1,315 @@
+package certdeck_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/a-novel-kit/certdeck"
+	"github.com/a-novel-kit/certdeck/clocktest"
+	certdeckmocks "github.com/a-novel-kit/certdeck/mocks"
+	"github.com/a-novel-kit/certdeck/stores"
+)
+
+func TestOCSPHandler(t *testing.T) {
+	serialStore := certdeckmocks.NewMockSerialStore(t)
+	serialStore.On("Insert", context.Background(), mock.Anything).Return(nil)
+
+	revocationStore := stores.NewMemoryRevocationStore()
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rootCert, err := certdeck.NewSigner(&certdeck.SignerConfig{SerialStore: serialStore}).Sign(
+		context.Background(), rootKey, nil,
+		&certdeck.Template{Exp: time.Hour, Name: pkix.Name{CommonName: "root"}},
+	)
+	require.NoError(t, err)
+
+	signer := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore: serialStore,
+		IssuerChain: []*x509.Certificate{rootCert},
+		IssuerKey:   rootKey,
+	})
+
+	issueLeaf := func(t *testing.T) *x509.Certificate {
+		t.Helper()
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		cert, err := signer.Sign(
+			context.Background(), key.Public(), nil,
+			&certdeck.Template{Exp: time.Hour, Name: pkix.Name{CommonName: "leaf"}, LeafOnly: true},
+		)
+		require.NoError(t, err)
+
+		return cert
+	}
+
+	goodLeaf := issueLeaf(t)
+	revokedLeaf := issueLeaf(t)
+
+	revokedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	require.NoError(t, revocationStore.Revoke(context.Background(), &certdeck.Revocation{
+		Serial:    revokedLeaf.SerialNumber,
+		RevokedAt: revokedAt,
+		Reason:    certdeck.RevocationReasonSuperseded,
+	}))
+
+	post := func(t *testing.T, url string, cert, issuer *x509.Certificate) []byte {
+		t.Helper()
+
+		request, err := ocsp.CreateRequest(cert, issuer, nil)
+		require.NoError(t, err)
+
+		resp, err := http.Post(url, "application/ocsp-request", bytes.NewReader(request))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/ocsp-response", resp.Header.Get("Content-Type"))
+
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return raw
+	}
+
+	clock := clocktest.New(time.Now())
+
+	handler, err := certdeck.NewOCSPHandler(&certdeck.OCSPHandlerConfig{
+		Issuer:           rootCert,
+		ResponderKey:     rootKey,
+		RevocationStore:  revocationStore,
+		ResponseValidity: time.Minute,
+		Clock:            clock,
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("good", func(t *testing.T) {
+		response, err := ocsp.ParseResponseForCert(post(t, server.URL, goodLeaf, rootCert), goodLeaf, rootCert)
+		require.NoError(t, err)
+		require.Equal(t, ocsp.Good, response.Status)
+		require.Equal(t, goodLeaf.SerialNumber, response.SerialNumber)
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		response, err := ocsp.ParseResponseForCert(post(t, server.URL, revokedLeaf, rootCert), revokedLeaf, rootCert)
+		require.NoError(t, err)
+		require.Equal(t, ocsp.Revoked, response.Status)
+		require.Equal(t, ocsp.Superseded, response.RevocationReason)
+		require.True(t, revokedAt.Equal(response.RevokedAt))
+	})
+
+	t.Run("get", func(t *testing.T) {
+		request, err := ocsp.CreateRequest(goodLeaf, rootCert, &ocsp.RequestOptions{Hash: crypto.SHA256})
+		require.NoError(t, err)
+
+		resp, err := http.Get(server.URL + "/" + base64.StdEncoding.EncodeToString(request))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NotEmpty(t, resp.Header.Get("Cache-Control"))
+
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		response, err := ocsp.ParseResponseForCert(raw, goodLeaf, rootCert)
+		require.NoError(t, err)
+		require.Equal(t, ocsp.Good, response.Status)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		first := post(t, server.URL, goodLeaf, rootCert)
+		second := post(t, server.URL, goodLeaf, rootCert)
+		require.Equal(t, first, second)
+
+		// Response expired, a new one is signed.
+		clock.Advance(time.Minute)
+
+		third := post(t, server.URL, goodLeaf, rootCert)
+		require.NotEqual(t, first, third)
+
+		response, err := ocsp.ParseResponseForCert(third, goodLeaf, rootCert)
+		require.NoError(t, err)
+		require.WithinDuration(t, clock.Now(), response.ThisUpdate, time.Second)
+	})
+
+	t.Run("cache limit", func(t *testing.T) {
+		limitedHandler, err := certdeck.NewOCSPHandler(&certdeck.OCSPHandlerConfig{
+			Issuer:             rootCert,
+			ResponderKey:       rootKey,
+			RevocationStore:    revocationStore,
+			ResponseValidity:   time.Minute,
+			MaxCachedResponses: 2,
+			Clock:              clock,
+		})
+		require.NoError(t, err)
+
+		limitedServer := httptest.NewServer(limitedHandler)
+		defer limitedServer.Close()
+
+		otherLeaf := issueLeaf(t)
+
+		good := post(t, limitedServer.URL, goodLeaf, rootCert)
+		revoked := post(t, limitedServer.URL, revokedLeaf, rootCert)
+		// Use the good response again, so the revoked one is the least recently used.
+		require.Equal(t, good, post(t, limitedServer.URL, goodLeaf, rootCert))
+
+		post(t, limitedServer.URL, otherLeaf, rootCert)
+
+		require.Equal(t, good, post(t, limitedServer.URL, goodLeaf, rootCert))
+		// ECDSA signatures are randomized, so a new signature gives a different response.
+		require.NotEqual(t, revoked, post(t, limitedServer.URL, revokedLeaf, rootCert))
+	})
+
+	t.Run("unknown issuer", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		otherRoot, err := certdeck.NewSigner(&certdeck.SignerConfig{SerialStore: serialStore}).Sign(
+			context.Background(), otherKey, nil,
+			&certdeck.Template{Exp: time.Hour, Name: pkix.Name{CommonName: "other"}},
+		)
+		require.NoError(t, err)
+
+		_, err = ocsp.ParseResponse(post(t, server.URL, goodLeaf, otherRoot), nil)
+		require.ErrorAs(t, err, new(ocsp.ResponseError))
+		require.Equal(t, ocsp.Unauthorized, err.(ocsp.ResponseError).Status)
+	})
+
+	t.Run("same key, other issuer name", func(t *testing.T) {
+		otherRoot, err := certdeck.NewSigner(&certdeck.SignerConfig{SerialStore: serialStore}).Sign(
+			context.Background(), rootKey, nil,
+			&certdeck.Template{Exp: time.Hour, Name: pkix.Name{CommonName: "other"}},
+		)
+		require.NoError(t, err)
+
+		_, err = ocsp.ParseResponse(post(t, server.URL, goodLeaf, otherRoot), nil)
+		require.ErrorAs(t, err, new(ocsp.ResponseError))
+		require.Equal(t, ocsp.Unauthorized, err.(ocsp.ResponseError).Status)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		resp, err := http.Post(server.URL, "application/ocsp-request", bytes.NewReader([]byte("foo")))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		raw, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		_, err = ocsp.ParseResponse(raw, nil)
+		require.ErrorAs(t, err, new(ocsp.ResponseError))
+		require.Equal(t, ocsp.Malformed, err.(ocsp.ResponseError).Status)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPut, server.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	})
+
+	t.Run("delegated responder", func(t *testing.T) {
+		responderKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		responderRaw, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "ocsp responder"},
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+		}, rootCert, responderKey.Public(), rootKey)
+		require.NoError(t, err)
+
+		responderCert, err := x509.ParseCertificate(responderRaw)
+		require.NoError(t, err)
+
+		// Responders must be issued by the issuer, for OCSP signing.
+		selfSignedTemplate := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      pkix.Name{CommonName: "ocsp responder"},
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning},
+		}
+
+		otherRaw, err := x509.CreateCertificate(
+			rand.Reader, selfSignedTemplate, selfSignedTemplate, responderKey.Public(), responderKey,
+		)
+		require.NoError(t, err)
+
+		otherIssuerCert, err := x509.ParseCertificate(otherRaw)
+		require.NoError(t, err)
+
+		serverAuthRaw, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(3),
+			Subject:      pkix.Name{CommonName: "ocsp responder"},
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}, rootCert, responderKey.Public(), rootKey)
+		require.NoError(t, err)
+
+		serverAuthCert, err := x509.ParseCertificate(serverAuthRaw)
+		require.NoError(t, err)
+
+		for _, invalid := range []*x509.Certificate{otherIssuerCert, serverAuthCert} {
+			_, err = certdeck.NewOCSPHandler(&certdeck.OCSPHandlerConfig{
+				Issuer:          rootCert,
+				ResponderCert:   invalid,
+				ResponderKey:    responderKey,
+				RevocationStore: revocationStore,
+			})
+			require.ErrorIs(t, err, certdeck.ErrInvalidOCSPResponder)
+		}
+
+		delegatedHandler, err := certdeck.NewOCSPHandler(&certdeck.OCSPHandlerConfig{
+			Issuer:          rootCert,
+			ResponderCert:   responderCert,
+			ResponderKey:    responderKey,
+			RevocationStore: revocationStore,
+		})
+		require.NoError(t, err)
+
+		delegatedServer := httptest.NewServer(delegatedHandler)
+		defer delegatedServer.Close()
+
+		raw := post(t, delegatedServer.URL, revokedLeaf, rootCert)
+
+		response, err := ocsp.ParseResponseForCert(raw, revokedLeaf, rootCert)
+		require.NoError(t, err)
+		require.Equal(t, ocsp.Revoked, response.Status)
+		require.NotNil(t, response.Certificate)
+		require.True(t, responderCert.Equal(response.Certificate))
+	})
+}
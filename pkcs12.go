@@ -0,0 +1,122 @@
+package certdeck
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+var ErrInvalidPKCS12 = errors.New("invalid PKCS#12 data")
+
+// PKCS12Encryption is the set of algorithms used to protect a PKCS#12 bundle.
+type PKCS12Encryption int
+
+const (
+	// PKCS12Modern encrypts the bundle with AES-256-CBC, using PBKDF2-HMAC-SHA256, and authenticates it with
+	// HMAC-SHA256. It is the default of OpenSSL 3 and Java 20, and can be read by Java 12 and Windows Server 2019
+	// or higher.
+	PKCS12Modern PKCS12Encryption = iota
+	// PKCS12LegacyDES encrypts the bundle with 3DES, and authenticates it with HMAC-SHA1. It is weak, and should
+	// only be used for consumers that cannot read PKCS12Modern bundles.
+	PKCS12LegacyDES
+)
+
+// PKCS12Options configures the encoding of a PKCS#12 bundle.
+type PKCS12Options struct {
+	// Encryption selects the algorithms used to protect the bundle.
+	//
+	// PKCS12Modern is used by default.
+	Encryption PKCS12Encryption
+	// Iterations is the number of iterations of the key derivation function.
+	//
+	// If 0, the default of the selected encryption is used (2048).
+	Iterations int
+}
+
+func (opts *PKCS12Options) encoder() (*pkcs12.Encoder, error) {
+	var encoder *pkcs12.Encoder
+
+	switch opts.Encryption {
+	case PKCS12Modern:
+		encoder = pkcs12.Modern2023
+	case PKCS12LegacyDES:
+		encoder = pkcs12.LegacyDES
+	default:
+		return nil, fmt.Errorf("%w: unknown encryption %d", ErrInvalidPKCS12, opts.Encryption)
+	}
+
+	if opts.Iterations > 0 {
+		encoder = encoder.WithIterations(opts.Iterations)
+	}
+
+	return encoder, nil
+}
+
+// RowToPKCS12 encodes the certificates and private key of a row to a PKCS#12 (PFX) bundle. The first certificate
+// of the row is the leaf, and must match the private key. The rest of the chain is stored as CA certificates.
+//
+// If opts is nil, the default options are used.
+func RowToPKCS12(row CollectionRow, password string, opts *PKCS12Options) ([]byte, error) {
+	if opts == nil {
+		opts = &PKCS12Options{}
+	}
+
+	certs := row.Certificates()
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: no certificate", ErrInvalidPKCS12)
+	}
+
+	key := row.Key()
+	if key == nil {
+		return nil, fmt.Errorf("%w: no private key", ErrInvalidPKCS12)
+	}
+
+	if err := MatchKey(key.Public(), certs); err != nil {
+		return nil, err
+	}
+
+	encoder, err := opts.encoder()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := encoder.Encode(key, certs[0], certs[1:], password)
+	if err != nil {
+		return nil, fmt.Errorf("encode pkcs12: %w", err)
+	}
+
+	return data, nil
+}
+
+// PKCS12ToRow decodes a PKCS#12 (PFX) bundle. The bundle must hold exactly one private key, and its certificate.
+// Other certificates are appended to the chain, in the order they appear in the bundle.
+//
+// Both modern (PBES2 with AES) and legacy (RC2 and 3DES) bundles are supported.
+func PKCS12ToRow(data []byte, password string) (*CollectionRowBase, error) {
+	rawKey, leaf, caCerts, err := pkcs12.DecodeChain(data, password)
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+		return nil, ErrIncorrectPassphrase
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPKCS12, err)
+	}
+
+	key, ok := rawKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported private key %T", ErrUnsupportedKeyFormat, rawKey)
+	}
+
+	row := &CollectionRowBase{
+		Certs:   append([]*x509.Certificate{leaf}, caCerts...),
+		CertKey: key,
+	}
+
+	if err = row.Fill(); err != nil {
+		return nil, fmt.Errorf("fill row: %w", err)
+	}
+
+	return row, nil
+}
@@ -0,0 +1,129 @@
+package certdeck_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	testcerts "github.com/a-novel-kit/certdeck/internal/certs"
+)
+
+func TestPKCS12ToRow(t *testing.T) {
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "Modern", data: testcerts.Chain1ModernP12},
+		{name: "Legacy", data: testcerts.Chain1LegacyP12},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			row, err := certdeck.PKCS12ToRow(testCase.data, testcerts.PKCS12Password)
+			require.NoError(t, err)
+			require.NoError(t, certdeck.Match(
+				[]*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert},
+				row.Certificates(),
+			))
+			require.True(t, testcerts.Chain1Key.(*rsa.PrivateKey).Equal(row.Key()))
+			require.NotEmpty(t, row.KeyPEM())
+			require.Len(t, row.CertificatesPEM(), 3)
+
+			_, err = certdeck.PKCS12ToRow(testCase.data, "wrong")
+			require.ErrorIs(t, err, certdeck.ErrIncorrectPassphrase)
+		})
+	}
+
+	t.Run("Garbage", func(t *testing.T) {
+		_, err := certdeck.PKCS12ToRow([]byte("foo"), "")
+		require.ErrorIs(t, err, certdeck.ErrInvalidPKCS12)
+	})
+}
+
+func TestRowToPKCS12(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name string
+
+		row  *certdeck.CollectionRowBase
+		opts *certdeck.PKCS12Options
+
+		expectErr error
+	}{
+		{
+			name: "Modern",
+			row: &certdeck.CollectionRowBase{
+				Certs:   []*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert},
+				CertKey: testcerts.Chain1Key,
+			},
+		},
+		{
+			name: "LegacyDES",
+			row: &certdeck.CollectionRowBase{
+				Certs:   []*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert},
+				CertKey: testcerts.Chain1Key,
+			},
+			opts: &certdeck.PKCS12Options{Encryption: certdeck.PKCS12LegacyDES, Iterations: 4096},
+		},
+		{
+			name: "KeyMismatch",
+			row: &certdeck.CollectionRowBase{
+				Certs:   []*x509.Certificate{testcerts.Chain1Cert},
+				CertKey: ecdsaKey,
+			},
+			expectErr: certdeck.ErrCertKeyMismatch,
+		},
+		{
+			name: "NoCertificate",
+			row: &certdeck.CollectionRowBase{
+				CertKey: ed25519Key,
+			},
+			expectErr: certdeck.ErrInvalidPKCS12,
+		},
+		{
+			name: "NoKey",
+			row: &certdeck.CollectionRowBase{
+				Certs: []*x509.Certificate{testcerts.Chain1Cert},
+			},
+			expectErr: certdeck.ErrInvalidPKCS12,
+		},
+		{
+			name: "UnknownEncryption",
+			row: &certdeck.CollectionRowBase{
+				Certs:   []*x509.Certificate{testcerts.Chain1Cert},
+				CertKey: testcerts.Chain1Key,
+			},
+			opts:      &certdeck.PKCS12Options{Encryption: certdeck.PKCS12Encryption(42)},
+			expectErr: certdeck.ErrInvalidPKCS12,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			data, err := certdeck.RowToPKCS12(testCase.row, "password", testCase.opts)
+			require.ErrorIs(t, err, testCase.expectErr)
+
+			if testCase.expectErr != nil {
+				return
+			}
+
+			decoded, err := certdeck.PKCS12ToRow(data, "password")
+			require.NoError(t, err)
+			require.NoError(t, certdeck.Match(testCase.row.Certs, decoded.Certificates()))
+			require.True(t, testCase.row.CertKey.(interface{ Equal(crypto.PrivateKey) bool }).Equal(decoded.Key()))
+		})
+	}
+}
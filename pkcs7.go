@@ -0,0 +1,170 @@
+package certdeck
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidPKCS7 = errors.New("invalid PKCS#7 data")
+
+var (
+	oidPKCS7Data       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidPKCS7SignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+)
+
+// ASN.1 structures from RFC 2315. Only the parts needed for certs-only bundles are decoded.
+
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// pkcs7RawSet holds an implicitly tagged SET, with its tag.
+type pkcs7RawSet struct {
+	Raw asn1.RawContent
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7ContentInfo
+	Certificates     pkcs7RawSet `asn1:"optional,tag:0"`
+	CRLs             pkcs7RawSet `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// pkcs7SignedDataOut is the encoding side of pkcs7SignedData. Implicitly tagged sets are built by hand.
+type pkcs7SignedDataOut struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7ContentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      asn1.RawValue
+}
+
+// elements returns the DER encoded elements of the set.
+func (set pkcs7RawSet) elements() ([][]byte, error) {
+	if len(set.Raw) == 0 {
+		return nil, nil
+	}
+
+	var outer asn1.RawValue
+	if _, err := asn1.Unmarshal(set.Raw, &outer); err != nil {
+		return nil, err
+	}
+
+	var elements [][]byte
+
+	for rest := outer.Bytes; len(rest) > 0; {
+		var element asn1.RawValue
+
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &element); err != nil {
+			return nil, err
+		}
+
+		elements = append(elements, element.FullBytes)
+	}
+
+	return elements, nil
+}
+
+// isPKCS7 checks whether the DER data looks like a PKCS#7 SignedData structure.
+func isPKCS7(data []byte) bool {
+	var contentInfo pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(data, &contentInfo); err != nil {
+		return false
+	}
+
+	return contentInfo.ContentType.Equal(oidPKCS7SignedData)
+}
+
+// parsePKCS7 returns the certificates and revocation lists of a DER encoded PKCS#7 SignedData structure. The
+// signatures, if any, are not checked.
+//
+// Only DER is supported. Some tools produce BER encoded bundles, with indefinite lengths, that must be converted
+// first (for example with "openssl pkcs7 -outform DER").
+func parsePKCS7(data []byte) ([]*x509.Certificate, []*x509.RevocationList, error) {
+	var contentInfo pkcs7ContentInfo
+
+	rest, err := asn1.Unmarshal(data, &contentInfo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidPKCS7, err)
+	}
+
+	if len(rest) > 0 {
+		return nil, nil, fmt.Errorf("%w: trailing data", ErrInvalidPKCS7)
+	}
+
+	if !contentInfo.ContentType.Equal(oidPKCS7SignedData) {
+		return nil, nil, fmt.Errorf("%w: unsupported content type %s", ErrInvalidPKCS7, contentInfo.ContentType)
+	}
+
+	// The raw content keeps its explicit [0] tag, the SignedData structure is inside.
+	var signedData pkcs7SignedData
+	if _, err = asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, nil, fmt.Errorf("%w: signed data: %w", ErrInvalidPKCS7, err)
+	}
+
+	rawCerts, err := signedData.Certificates.elements()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: certificates: %w", ErrInvalidPKCS7, err)
+	}
+
+	certs, err := DERToCerts(rawCerts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: parse certificate: %w", ErrInvalidPKCS7, err)
+	}
+
+	rawCRLs, err := signedData.CRLs.elements()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: revocation lists: %w", ErrInvalidPKCS7, err)
+	}
+
+	crls := make([]*x509.RevocationList, len(rawCRLs))
+	for pos, rawCRL := range rawCRLs {
+		if crls[pos], err = DERToCRL(rawCRL); err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrInvalidPKCS7, err)
+		}
+	}
+
+	return certs, crls, nil
+}
+
+// marshalPKCS7 encodes certificates as a degenerate, certs-only, PKCS#7 SignedData structure, with no content and
+// no signer. Certificates are stored in the given order.
+func marshalPKCS7(certs []*x509.Certificate) ([]byte, error) {
+	signedData, err := asn1.Marshal(pkcs7SignedDataOut{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{},
+		ContentInfo:      pkcs7ContentInfo{ContentType: oidPKCS7Data},
+		Certificates: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      CertsToDERInline(certs...),
+		},
+		SignerInfos: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal signed data: %w", err)
+	}
+
+	contentInfo, err := asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidPKCS7SignedData,
+		Content: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      signedData,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal content info: %w", err)
+	}
+
+	return contentInfo, nil
+}
@@ -1,6 +1,8 @@
 package providers
 
 import (
+	"context"
+	"crypto"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -27,6 +29,21 @@ func SortName(files []os.FileInfo) []os.FileInfo {
 	return files
 }
 
+// parseKey parses a PEM or DER encoded private key. If a passphrase source is provided, the key is decrypted
+// with it.
+func parseKey(data []byte, passphrase func() ([]byte, error)) (crypto.Signer, error) {
+	if passphrase == nil {
+		return certdeck.PEMOrDerToKey(data)
+	}
+
+	secret, err := passphrase()
+	if err != nil {
+		return nil, fmt.Errorf("get passphrase: %w", err)
+	}
+
+	return certdeck.EncryptedPEMOrDERToKey(data, secret)
+}
+
 type fileProvider struct {
 	fs fs.FS
 
@@ -37,6 +54,10 @@ type fileProvider struct {
 
 	sortCerts func([]os.FileInfo) []os.FileInfo
 	sortKeys  func([]os.FileInfo) []os.FileInfo
+
+	passphrase func() ([]byte, error)
+
+	orderChain bool
 }
 
 func (provider *fileProvider) ID() string {
@@ -44,6 +65,10 @@ func (provider *fileProvider) ID() string {
 }
 
 func (provider *fileProvider) Retrieve() (certdeck.CollectionRow, error) {
+	return provider.RetrieveContext(context.Background())
+}
+
+func (provider *fileProvider) RetrieveContext(ctx context.Context) (certdeck.CollectionRow, error) {
 	var certFiles []os.FileInfo
 	var keyFiles []os.FileInfo
 
@@ -52,6 +77,10 @@ func (provider *fileProvider) Retrieve() (certdeck.CollectionRow, error) {
 			return fmt.Errorf("walk directory: %w", err)
 		}
 
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
 		if d.IsDir() {
 			return nil
 		}
@@ -87,6 +116,10 @@ func (provider *fileProvider) Retrieve() (certdeck.CollectionRow, error) {
 	// Read files.
 	certsRaw := make([][]byte, len(certFiles))
 	for pos, certFile := range certFiles {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		certData, err := fs.ReadFile(provider.fs, certFile.Name())
 		if err != nil {
 			return nil, fmt.Errorf("read certificate file %s: %w", certFile.Name(), err)
@@ -102,6 +135,10 @@ func (provider *fileProvider) Retrieve() (certdeck.CollectionRow, error) {
 		return nil, errors.New("no certificate file found")
 	}
 
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	keyRaw, err := fs.ReadFile(provider.fs, keyFiles[0].Name())
 	if err != nil {
 		return nil, fmt.Errorf("read key file %s: %w", keyFiles[0].Name(), err)
@@ -111,11 +148,17 @@ func (provider *fileProvider) Retrieve() (certdeck.CollectionRow, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parse certificates: %w", err)
 	}
-	key, err := certdeck.PEMOrDerToKey(keyRaw)
+	key, err := parseKey(keyRaw, provider.passphrase)
 	if err != nil {
 		return nil, fmt.Errorf("parse private key: %w", err)
 	}
 
+	if provider.orderChain {
+		if certs, err = certdeck.OrderChain(key.Public(), certs); err != nil {
+			return nil, fmt.Errorf("order chain: %w", err)
+		}
+	}
+
 	keyPEM, err := certdeck.KeyToPEM(key)
 	if err != nil {
 		return nil, fmt.Errorf("convert private key to PEM: %w", err)
@@ -159,6 +202,18 @@ type FileProviderConfig struct {
 	//
 	// SortCreatedAt is used by default.
 	SortKeys func([]os.FileInfo) []os.FileInfo
+
+	// Passphrase returns the passphrase used to decrypt the private key. It is called on every retrieval, so
+	// the passphrase can be rotated along with the key.
+	//
+	// If nil, the key must not be encrypted.
+	Passphrase func() ([]byte, error)
+
+	// OrderChain reorders the certificates after they are parsed, using certdeck.OrderChain. The leaf is the
+	// certificate matching the private key, and retrieval fails if some certificates are not part of its chain.
+	//
+	// When set, SortCerts only affects the order in which files are read.
+	OrderChain bool
 }
 
 // NewFile returns a new certdeck.CertsProvider that reads certificates and keys from a filesystem.
@@ -195,5 +250,8 @@ func NewFile(config *FileProviderConfig) (certdeck.CertsProvider, error) {
 
 		sortCerts: sortCerts,
 		sortKeys:  sortKeys,
+
+		passphrase: config.Passphrase,
+		orderChain: config.OrderChain,
 	}, nil
 }
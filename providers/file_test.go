@@ -1,6 +1,7 @@
 package providers_test
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/x509"
 	"regexp"
@@ -103,4 +104,104 @@ func TestFileUpdater(t *testing.T) {
 		_, err = updater.Retrieve()
 		require.Error(t, err)
 	})
+
+	t.Run("encrypted key", func(t *testing.T) {
+		updater, err := providers.NewFile(&providers.FileProviderConfig{
+			FS: certs.FS,
+
+			ID: "foo",
+
+			CertsPattern: regexp.MustCompile(`^chain-1-cert\.pem$`),
+			KeysPattern:  regexp.MustCompile(`encrypted-chain-1-pbkdf2\.pem$`),
+
+			Passphrase: func() ([]byte, error) {
+				return []byte(certs.EncryptedKeyPassphrase), nil
+			},
+		})
+		require.NoError(t, err)
+
+		row, err := updater.Retrieve()
+		require.NoError(t, err)
+		require.True(t, certs.Chain1Key.(*rsa.PrivateKey).Equal(row.Key()))
+
+		keyFromPEM, err := certdeck.PEMToKey(row.KeyPEM())
+		require.NoError(t, err)
+		require.True(t, certs.Chain1Key.(*rsa.PrivateKey).Equal(keyFromPEM))
+	})
+
+	t.Run("encrypted key without passphrase", func(t *testing.T) {
+		updater, err := providers.NewFile(&providers.FileProviderConfig{
+			FS: certs.FS,
+
+			ID: "foo",
+
+			CertsPattern: regexp.MustCompile(`^chain-1-cert\.pem$`),
+			KeysPattern:  regexp.MustCompile(`encrypted-chain-1-pbkdf2\.pem$`),
+		})
+		require.NoError(t, err)
+
+		_, err = updater.Retrieve()
+		require.ErrorIs(t, err, certdeck.ErrEncryptedKey)
+	})
+	t.Run("order chain", func(t *testing.T) {
+		updater, err := providers.NewFile(&providers.FileProviderConfig{
+			FS: certs.FS,
+
+			ID: "foo",
+
+			CertsPattern: regexp.MustCompile(`^(chain-.*-cert|cacert)\.pem$`),
+			KeysPattern:  regexp.MustCompile(`^chain-1-keypair\.pem$`),
+
+			// Read the files in reverse order.
+			SortCerts: providers.SortName,
+
+			OrderChain: true,
+		})
+		require.NoError(t, err)
+
+		row, err := updater.Retrieve()
+		require.NoError(t, err)
+		require.NoError(t, certdeck.Match(
+			[]*x509.Certificate{certs.Chain1Cert, certs.Chain2Cert, certs.Chain3Cert, certs.CACert},
+			row.Certificates(),
+		))
+
+		certsFromPEM, err := certdeck.PEMToCerts(row.CertificatesPEM())
+		require.NoError(t, err)
+		require.NoError(t, certdeck.Match(row.Certificates(), certsFromPEM))
+	})
+
+	t.Run("order chain with unused certificates", func(t *testing.T) {
+		updater, err := providers.NewFile(&providers.FileProviderConfig{
+			FS: certs.FS,
+
+			ID: "foo",
+
+			CertsPattern: regexp.MustCompile(`^chain-.*-cert\.pem$`),
+			KeysPattern:  regexp.MustCompile(`^chain-3-keypair\.pem$`),
+
+			OrderChain: true,
+		})
+		require.NoError(t, err)
+
+		_, err = updater.Retrieve()
+		require.ErrorIs(t, err, certdeck.ErrUnusedCertificates)
+	})
+	t.Run("canceled context", func(t *testing.T) {
+		updater, err := providers.NewFile(&providers.FileProviderConfig{
+			FS: certs.FS,
+
+			ID: "foo",
+
+			CertsPattern: regexp.MustCompile(`chain-.*-cert\.pem$`),
+			KeysPattern:  regexp.MustCompile(`chain-.*-keypair\.pem$`),
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = updater.(certdeck.ContextCertsProvider).RetrieveContext(ctx)
+		require.ErrorIs(t, err, context.Canceled)
+	})
 }
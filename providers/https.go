@@ -1,9 +1,14 @@
 package providers
 
 import (
+	"context"
+	"crypto"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/a-novel-kit/certdeck"
 )
@@ -12,67 +17,162 @@ type httpsProvider struct {
 	id       string
 	certsReq func() (*http.Request, error)
 	keyReq   func() (*http.Request, error)
+
+	passphrase func() ([]byte, error)
+
+	orderChain bool
 }
 
 func (provider *httpsProvider) ID() string {
 	return provider.id
 }
 
-func (provider *httpsProvider) downloadCerts() ([]byte, error) {
+// cacheMaxAge reads the freshness lifetime of a response, from its Cache-Control or Expires headers. It returns
+// false when the response does not tell. A zero lifetime means the response must not be cached.
+func cacheMaxAge(header http.Header) (time.Duration, bool) {
+	if cacheControl := header.Get("Cache-Control"); cacheControl != "" {
+		for _, directive := range strings.Split(cacheControl, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+
+			switch strings.ToLower(name) {
+			case "no-cache", "no-store":
+				return 0, true
+			case "max-age":
+				seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+				if err != nil {
+					// Invalid lifetimes make the response stale (RFC 9111).
+					return 0, true
+				}
+
+				// Age is the time the response already spent in caches.
+				age, _ := strconv.Atoi(header.Get("Age"))
+
+				return max(time.Duration(seconds-age)*time.Second, 0), true
+			}
+		}
+	}
+
+	if header.Get("Expires") == "" {
+		return 0, false
+	}
+
+	expires, err := http.ParseTime(header.Get("Expires"))
+	if err != nil {
+		// Invalid dates, like "0", mean the response is already expired (RFC 9111).
+		return 0, true
+	}
+
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		date = time.Now()
+	}
+
+	return max(expires.Sub(date), 0), true
+}
+
+// cacheHint is the freshness lifetime of a response, as returned by cacheMaxAge.
+type cacheHint struct {
+	maxAge time.Duration
+	ok     bool
+}
+
+func (provider *httpsProvider) downloadCerts(ctx context.Context) ([]byte, cacheHint, error) {
 	req, err := provider.certsReq()
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, cacheHint{}, fmt.Errorf("create request: %w", err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
-		return nil, err
+		return nil, cacheHint{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, cacheHint{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read certificate chain: %w", err)
+		return nil, cacheHint{}, fmt.Errorf("read certificate chain: %w", err)
 	}
 
-	return data, nil
+	maxAge, ok := cacheMaxAge(resp.Header)
+
+	return data, cacheHint{maxAge: maxAge, ok: ok}, nil
 }
 
-func (provider *httpsProvider) downloadKey() ([]byte, error) {
+func (provider *httpsProvider) downloadKey(ctx context.Context) ([]byte, cacheHint, error) {
 	req, err := provider.keyReq()
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, cacheHint{}, fmt.Errorf("create request: %w", err)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
-		return nil, err
+		return nil, cacheHint{}, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, cacheHint{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read key: %w", err)
+		return nil, cacheHint{}, fmt.Errorf("read key: %w", err)
+	}
+
+	maxAge, ok := cacheMaxAge(resp.Header)
+
+	return data, cacheHint{maxAge: maxAge, ok: ok}, nil
+}
+
+// parseKey parses the downloaded key, and returns it with its unencrypted PEM encoding.
+func (provider *httpsProvider) parseKey(keyPEM []byte) (crypto.Signer, []byte, error) {
+	if provider.passphrase == nil {
+		key, err := certdeck.PEMToKey(keyPEM)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse private key: %w", err)
+		}
+
+		return key, keyPEM, nil
+	}
+
+	passphrase, err := provider.passphrase()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get passphrase: %w", err)
+	}
+
+	key, err := certdeck.EncryptedPEMToKey(keyPEM, passphrase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	// Never expose the encrypted key, as its passphrase is not part of the row.
+	keyPEM, err = certdeck.KeyToPEM(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("convert private key to PEM: %w", err)
 	}
 
-	return data, nil
+	return key, keyPEM, nil
 }
 
 func (provider *httpsProvider) Retrieve() (certdeck.CollectionRow, error) {
-	certsPEMInline, err := provider.downloadCerts()
+	return provider.RetrieveContext(context.Background())
+}
+
+// RetrieveContext downloads the certificates and key. The context replaces the one of the requests.
+//
+// The shortest freshness lifetime of both responses is used as the TTL hint of the row. Responses without a
+// lifetime are ignored.
+func (provider *httpsProvider) RetrieveContext(ctx context.Context) (certdeck.CollectionRow, error) {
+	certsPEMInline, certsHint, err := provider.downloadCerts(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("download certificates: %w", err)
 	}
 
-	keyPEM, err := provider.downloadKey()
+	keyPEM, keyHint, err := provider.downloadKey(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("download key: %w", err)
 	}
@@ -82,17 +182,36 @@ func (provider *httpsProvider) Retrieve() (certdeck.CollectionRow, error) {
 		return nil, fmt.Errorf("parse certificates: %w", err)
 	}
 
-	key, err := certdeck.PEMToKey(keyPEM)
+	key, keyPEM, err := provider.parseKey(keyPEM)
 	if err != nil {
-		return nil, fmt.Errorf("parse private key: %w", err)
+		return nil, err
+	}
+
+	if provider.orderChain {
+		if certs, err = certdeck.OrderChain(key.Public(), certs); err != nil {
+			return nil, fmt.Errorf("order chain: %w", err)
+		}
 	}
 
-	return &certdeck.CollectionRowBase{
+	row := &certdeck.CollectionRowBase{
 		Certs:      certs,
 		CertKey:    key,
 		CertsPEM:   certdeck.CertsToPEM(certs...),
 		CertKeyPEM: keyPEM,
-	}, nil
+	}
+
+	hinted := false
+
+	for _, hint := range []cacheHint{certsHint, keyHint} {
+		if hint.ok && (!hinted || hint.maxAge < row.MaxAge) {
+			row.MaxAge = hint.maxAge
+			hinted = true
+		}
+	}
+
+	row.NoCache = hinted && row.MaxAge == 0
+
+	return row, nil
 }
 
 type HTTPSProviderConfig struct {
@@ -100,9 +219,22 @@ type HTTPSProviderConfig struct {
 	ID string
 
 	// CertsReq returns a request to download the certificate chain.
+	//
+	// The context of the request is replaced with the one passed to RetrieveContext, or context.Background
+	// when using Retrieve. This also applies to KeyReq.
 	CertsReq func() (*http.Request, error)
 	// KeyReq returns a request to download the private key.
 	KeyReq func() (*http.Request, error)
+
+	// Passphrase returns the passphrase used to decrypt the private key. It is called on every retrieval, so
+	// the passphrase can be rotated along with the key.
+	//
+	// If nil, the key must not be encrypted.
+	Passphrase func() ([]byte, error)
+
+	// OrderChain reorders the downloaded certificates, using certdeck.OrderChain. The leaf is the certificate
+	// matching the private key, and retrieval fails if some certificates are not part of its chain.
+	OrderChain bool
 }
 
 func NewHTTPS(config *HTTPSProviderConfig) certdeck.CertsProvider {
@@ -110,5 +242,8 @@ func NewHTTPS(config *HTTPSProviderConfig) certdeck.CertsProvider {
 		id:       config.ID,
 		certsReq: config.CertsReq,
 		keyReq:   config.KeyReq,
+
+		passphrase: config.Passphrase,
+		orderChain: config.OrderChain,
 	}
 }
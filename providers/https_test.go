@@ -1,11 +1,14 @@
 package providers_test
 
 import (
+	"bytes"
+	"context"
 	"crypto/rsa"
 	"crypto/x509"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -66,6 +69,217 @@ func TestHTTPS(t *testing.T) {
 		require.True(t, certs.Chain1Key.(*rsa.PrivateKey).Equal(keyFromPEM))
 	})
 
+	t.Run("order chain", func(t *testing.T) {
+		certsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(bytes.Join([][]byte{certs.Chain3CertPEM, certs.Chain1CertPEM, certs.Chain2CertPEM}, nil))
+		})
+		keysHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(certs.Chain1KeypairPEM)
+		})
+
+		certsServer := httptest.NewServer(certsHandler)
+		defer certsServer.Close()
+		keysServer := httptest.NewServer(keysHandler)
+		defer keysServer.Close()
+
+		updater := providers.NewHTTPS(&providers.HTTPSProviderConfig{
+			ID: "foo",
+
+			CertsReq: func() (*http.Request, error) {
+				return http.NewRequest(http.MethodGet, certsServer.URL, nil)
+			},
+			KeyReq: func() (*http.Request, error) {
+				return http.NewRequest(http.MethodGet, keysServer.URL, nil)
+			},
+
+			OrderChain: true,
+		})
+
+		row, err := updater.Retrieve()
+		require.NoError(t, err)
+		require.NoError(t, certdeck.Match(
+			[]*x509.Certificate{certs.Chain1Cert, certs.Chain2Cert, certs.Chain3Cert},
+			row.Certificates(),
+		))
+	})
+
+	t.Run("cache headers", func(t *testing.T) {
+		date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		testCases := []struct {
+			name string
+
+			certsHeaders map[string]string
+			keyHeaders   map[string]string
+
+			expect      time.Duration
+			expectNoTTL bool
+		}{
+			{
+				name:         "max-age",
+				certsHeaders: map[string]string{"Cache-Control": "public, max-age=600"},
+				keyHeaders:   map[string]string{"Cache-Control": "max-age=300", "Age": "100"},
+				expect:       200 * time.Second,
+			},
+			{
+				name: "expires",
+				certsHeaders: map[string]string{
+					"Expires": date.Add(time.Hour).Format(http.TimeFormat),
+					"Date":    date.Format(http.TimeFormat),
+				},
+				keyHeaders: map[string]string{"Cache-Control": "max-age=7200"},
+				expect:     time.Hour,
+			},
+			{
+				name:         "max-age takes precedence over expires",
+				certsHeaders: map[string]string{"Cache-Control": "max-age=60", "Expires": "0"},
+				keyHeaders:   map[string]string{"Cache-Control": "max-age=60"},
+				expect:       time.Minute,
+			},
+			{
+				name:         "no-cache",
+				certsHeaders: map[string]string{"Cache-Control": "no-cache, max-age=600"},
+				keyHeaders:   map[string]string{"Cache-Control": "max-age=600"},
+				expect:       0,
+			},
+			{
+				name:         "no-store",
+				certsHeaders: map[string]string{"Cache-Control": "no-store"},
+				expect:       0,
+			},
+			{
+				name:         "expired",
+				certsHeaders: map[string]string{"Expires": "0"},
+				keyHeaders:   map[string]string{"Cache-Control": "max-age=600"},
+				expect:       0,
+			},
+			{
+				name:         "missing on one response",
+				certsHeaders: map[string]string{"Cache-Control": "max-age=600"},
+				expect:       10 * time.Minute,
+			},
+			{
+				name:        "missing on both responses",
+				expectNoTTL: true,
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				certsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					for key, value := range testCase.certsHeaders {
+						w.Header().Set(key, value)
+					}
+
+					w.WriteHeader(http.StatusOK)
+					_, _ = w.Write(certs.Chain1CertPEM)
+				}))
+				defer certsServer.Close()
+				keysServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					for key, value := range testCase.keyHeaders {
+						w.Header().Set(key, value)
+					}
+
+					w.WriteHeader(http.StatusOK)
+					_, _ = w.Write(certs.Chain1KeypairPEM)
+				}))
+				defer keysServer.Close()
+
+				updater := providers.NewHTTPS(&providers.HTTPSProviderConfig{
+					ID: "foo",
+
+					CertsReq: func() (*http.Request, error) {
+						return http.NewRequest(http.MethodGet, certsServer.URL, nil)
+					},
+					KeyReq: func() (*http.Request, error) {
+						return http.NewRequest(http.MethodGet, keysServer.URL, nil)
+					},
+				})
+
+				row, err := updater.Retrieve()
+				require.NoError(t, err)
+
+				hint, ok := row.(certdeck.TTLHint)
+				require.True(t, ok)
+
+				ttl, ok := hint.TTL()
+				require.Equal(t, !testCase.expectNoTTL, ok)
+
+				if !testCase.expectNoTTL {
+					require.Equal(t, testCase.expect, ttl)
+				}
+			})
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		release := make(chan struct{})
+
+		certsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer certsServer.Close()
+		defer close(release)
+
+		updater := providers.NewHTTPS(&providers.HTTPSProviderConfig{
+			ID: "foo",
+
+			CertsReq: func() (*http.Request, error) {
+				return http.NewRequest(http.MethodGet, certsServer.URL, nil)
+			},
+			KeyReq: func() (*http.Request, error) {
+				return http.NewRequest(http.MethodGet, certsServer.URL, nil)
+			},
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := certdeck.RetrieveContext(ctx, updater)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("encrypted key", func(t *testing.T) {
+		certsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(certs.Chain1CertPEM)
+		})
+		keysHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(certs.EncryptedChain1KeyScryptPEM)
+		})
+
+		certsServer := httptest.NewServer(certsHandler)
+		defer certsServer.Close()
+		keysServer := httptest.NewServer(keysHandler)
+		defer keysServer.Close()
+
+		updater := providers.NewHTTPS(&providers.HTTPSProviderConfig{
+			ID: "foo",
+
+			CertsReq: func() (*http.Request, error) {
+				return http.NewRequest(http.MethodGet, certsServer.URL, nil)
+			},
+			KeyReq: func() (*http.Request, error) {
+				return http.NewRequest(http.MethodGet, keysServer.URL, nil)
+			},
+			Passphrase: func() ([]byte, error) {
+				return []byte(certs.EncryptedKeyPassphrase), nil
+			},
+		})
+
+		row, err := updater.Retrieve()
+		require.NoError(t, err)
+		require.True(t, certs.Chain1Key.(*rsa.PrivateKey).Equal(row.Key()))
+
+		// The row exposes the decrypted key.
+		keyFromPEM, err := certdeck.PEMToKey(row.KeyPEM())
+		require.NoError(t, err)
+		require.True(t, certs.Chain1Key.(*rsa.PrivateKey).Equal(keyFromPEM))
+	})
+
 	t.Run("key server error", func(t *testing.T) {
 		certsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -0,0 +1,107 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/a-novel-kit/certdeck"
+)
+
+type pkcs12Provider struct {
+	fs   fs.FS
+	path string
+
+	id string
+
+	passphrase func() ([]byte, error)
+
+	orderChain bool
+}
+
+func (provider *pkcs12Provider) ID() string {
+	return provider.id
+}
+
+func (provider *pkcs12Provider) Retrieve() (certdeck.CollectionRow, error) {
+	return provider.RetrieveContext(context.Background())
+}
+
+func (provider *pkcs12Provider) RetrieveContext(ctx context.Context) (certdeck.CollectionRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := fs.ReadFile(provider.fs, provider.path)
+	if err != nil {
+		return nil, fmt.Errorf("read pkcs12 file %s: %w", provider.path, err)
+	}
+
+	var passphrase []byte
+	if provider.passphrase != nil {
+		passphrase, err = provider.passphrase()
+		if err != nil {
+			return nil, fmt.Errorf("get passphrase: %w", err)
+		}
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	row, err := certdeck.PKCS12ToRow(data, string(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("parse pkcs12 file %s: %w", provider.path, err)
+	}
+
+	if provider.orderChain {
+		if row.Certs, err = certdeck.OrderChain(row.Key().Public(), row.Certs); err != nil {
+			return nil, fmt.Errorf("order chain: %w", err)
+		}
+
+		row.CertsPEM = certdeck.CertsToPEM(row.Certs...)
+	}
+
+	return row, nil
+}
+
+type PKCS12ProviderConfig struct {
+	// FS is the file system to read the bundle from.
+	FS fs.FS
+	// Path of the bundle in FS.
+	Path string
+
+	// ID is the identifier of the updater.
+	ID string
+
+	// Passphrase returns the password of the bundle. It is called on every retrieval, so the password can be
+	// rotated along with the bundle.
+	//
+	// If nil, an empty password is used.
+	Passphrase func() ([]byte, error)
+
+	// OrderChain reorders the certificates of the bundle, using certdeck.OrderChain. Bundles do not guarantee the
+	// order of their CA certificates, and retrieval fails if some of them are not part of the chain of the leaf.
+	OrderChain bool
+}
+
+// NewPKCS12 returns a new certdeck.CertsProvider that reads a PKCS#12 (PFX) bundle from a filesystem.
+//
+// The bundle must hold the private key and its certificate. Other certificates in the bundle are used as the
+// rest of the chain.
+func NewPKCS12(config *PKCS12ProviderConfig) (certdeck.CertsProvider, error) {
+	if config.FS == nil || config.Path == "" {
+		return nil, errors.New("pkcs12 provider requires a filesystem and a path")
+	}
+
+	return &pkcs12Provider{
+		fs:   config.FS,
+		path: config.Path,
+
+		id: config.ID,
+
+		passphrase: config.Passphrase,
+		orderChain: config.OrderChain,
+	}, nil
+}
@@ -0,0 +1,138 @@
+package providers_test
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	"github.com/a-novel-kit/certdeck/internal/certs"
+	"github.com/a-novel-kit/certdeck/providers"
+)
+
+func TestPKCS12(t *testing.T) {
+	t.Run("basic", func(t *testing.T) {
+		updater, err := providers.NewPKCS12(&providers.PKCS12ProviderConfig{
+			FS:   certs.FS,
+			Path: "chain-1-modern.p12",
+
+			ID: "foo",
+
+			Passphrase: func() ([]byte, error) {
+				return []byte(certs.PKCS12Password), nil
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "foo", updater.ID())
+
+		row, err := updater.Retrieve()
+		require.NoError(t, err)
+		require.NoError(t, certdeck.Match(
+			[]*x509.Certificate{certs.Chain1Cert, certs.Chain2Cert, certs.Chain3Cert},
+			row.Certificates(),
+		))
+		require.True(t, certs.Chain1Key.(*rsa.PrivateKey).Equal(row.Key()))
+
+		keyFromPEM, err := certdeck.PEMToKey(row.KeyPEM())
+		require.NoError(t, err)
+		require.True(t, certs.Chain1Key.(*rsa.PrivateKey).Equal(keyFromPEM))
+	})
+
+	t.Run("order chain", func(t *testing.T) {
+		newBundle := func(t *testing.T, row *certdeck.CollectionRowBase) fstest.MapFS {
+			t.Helper()
+
+			data, err := certdeck.RowToPKCS12(row, certs.PKCS12Password, nil)
+			require.NoError(t, err)
+
+			return fstest.MapFS{"bundle.p12": &fstest.MapFile{Data: data}}
+		}
+
+		newUpdater := func(t *testing.T, bundle fstest.MapFS, orderChain bool) certdeck.CertsProvider {
+			t.Helper()
+
+			updater, err := providers.NewPKCS12(&providers.PKCS12ProviderConfig{
+				FS:   bundle,
+				Path: "bundle.p12",
+
+				ID: "foo",
+
+				Passphrase: func() ([]byte, error) {
+					return []byte(certs.PKCS12Password), nil
+				},
+
+				OrderChain: orderChain,
+			})
+			require.NoError(t, err)
+
+			return updater
+		}
+
+		shuffled := newBundle(t, &certdeck.CollectionRowBase{
+			Certs:   []*x509.Certificate{certs.Chain1Cert, certs.Chain3Cert, certs.Chain2Cert},
+			CertKey: certs.Chain1Key,
+		})
+
+		// The bundle order is kept by default.
+		row, err := newUpdater(t, shuffled, false).Retrieve()
+		require.NoError(t, err)
+		require.Error(t, certdeck.Match(
+			[]*x509.Certificate{certs.Chain1Cert, certs.Chain2Cert, certs.Chain3Cert},
+			row.Certificates(),
+		))
+
+		row, err = newUpdater(t, shuffled, true).Retrieve()
+		require.NoError(t, err)
+		require.NoError(t, certdeck.Match(
+			[]*x509.Certificate{certs.Chain1Cert, certs.Chain2Cert, certs.Chain3Cert},
+			row.Certificates(),
+		))
+
+		certsFromPEM, err := certdeck.PEMToCerts(row.CertificatesPEM())
+		require.NoError(t, err)
+		require.NoError(t, certdeck.Match(row.Certificates(), certsFromPEM))
+
+		// Chain 1 is not part of the chain of chain 2.
+		unused := newBundle(t, &certdeck.CollectionRowBase{
+			Certs:   []*x509.Certificate{certs.Chain2Cert, certs.Chain1Cert, certs.Chain3Cert},
+			CertKey: certs.Chain2Key,
+		})
+
+		_, err = newUpdater(t, unused, true).Retrieve()
+		require.ErrorIs(t, err, certdeck.ErrUnusedCertificates)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		updater, err := providers.NewPKCS12(&providers.PKCS12ProviderConfig{
+			FS:   certs.FS,
+			Path: "chain-1-modern.p12",
+
+			ID: "foo",
+		})
+		require.NoError(t, err)
+
+		_, err = updater.Retrieve()
+		require.ErrorIs(t, err, certdeck.ErrIncorrectPassphrase)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		updater, err := providers.NewPKCS12(&providers.PKCS12ProviderConfig{
+			FS:   certs.FS,
+			Path: "foo.p12",
+
+			ID: "foo",
+		})
+		require.NoError(t, err)
+
+		_, err = updater.Retrieve()
+		require.Error(t, err)
+	})
+
+	t.Run("no path", func(t *testing.T) {
+		_, err := providers.NewPKCS12(&providers.PKCS12ProviderConfig{FS: certs.FS})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,148 @@
+package certdeck
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+var ErrCollectionClosed = errors.New("collection is closed")
+
+const (
+	// DefaultRefreshJitter is the default fraction of the refresh interval, that is randomly added or removed from
+	// each wait.
+	DefaultRefreshJitter = 0.1
+	// DefaultRefreshBackoff is the default delay before retrying a failed background refresh.
+	DefaultRefreshBackoff = time.Second
+)
+
+// refresher keeps a single row refreshed in the background.
+type refresher struct {
+	provider CertsProvider
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// jitter randomly moves a duration by up to the jitter fraction of itself.
+func (collection *collectionImpl) jitter(d time.Duration) time.Duration {
+	if collection.refreshJitter <= 0 {
+		return d
+	}
+
+	return d + time.Duration((rand.Float64()*2-1)*collection.refreshJitter*float64(d))
+}
+
+// nextRefresh returns how long to wait before refreshing a row that was just fetched. Rows with a short TTL are
+// refreshed more often, and the row is refreshed early when its leaf expires before the next interval.
+func (collection *collectionImpl) nextRefresh(name string, row CollectionRow) time.Duration {
+	interval := collection.refreshInterval
+
+	collection.RLock()
+	// Rows that must not be cached are fetched again by every call anyway.
+	if ttl, ok := collection.cacheTTLs[name]; ok && ttl > 0 {
+		interval = min(interval, ttl/2)
+	}
+	collection.RUnlock()
+
+	wait := max(collection.jitter(interval), collection.refreshBackoff)
+
+	certs := row.Certificates()
+	if len(certs) == 0 {
+		return wait
+	}
+
+	untilExpiry := certs[0].NotAfter.Add(-collection.refreshBeforeExpiry).Sub(collection.clock.Now())
+	if untilExpiry < wait {
+		// Do not spin when the provider keeps returning a certificate that is about to expire.
+		wait = max(untilExpiry, collection.refreshBackoff)
+	}
+
+	return wait
+}
+
+func (collection *collectionImpl) runRefresher(ctx context.Context, name string, current *refresher) {
+	defer close(current.done)
+
+	backoff := collection.refreshBackoff
+
+	// The first refresh happens immediately, to warm the cache.
+	var wait time.Duration
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-collection.clock.After(wait):
+		}
+
+		row, err := collection.fetch(ctx, name, current.provider)
+
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			wait = collection.jitter(backoff)
+			backoff = min(backoff*2, max(collection.refreshInterval, collection.refreshBackoff))
+		default:
+			wait = collection.nextRefresh(name, row)
+			backoff = collection.refreshBackoff
+		}
+	}
+}
+
+func (collection *collectionImpl) Register(provider CertsProvider) error {
+	name := provider.ID()
+
+	collection.Lock()
+	defer collection.Unlock()
+
+	if collection.closed {
+		return ErrCollectionClosed
+	}
+
+	if _, ok := collection.refreshers[name]; ok {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	current := &refresher{provider: provider, cancel: cancel, done: make(chan struct{})}
+
+	collection.refreshers[name] = current
+	collection.cacheUpdaters[name] = provider
+
+	go collection.runRefresher(ctx, name, current)
+
+	return nil
+}
+
+func (collection *collectionImpl) Unregister(id string) {
+	collection.Lock()
+	current, ok := collection.refreshers[id]
+	delete(collection.refreshers, id)
+	collection.Unlock()
+
+	if ok {
+		current.cancel()
+		<-current.done
+	}
+}
+
+func (collection *collectionImpl) Close() error {
+	collection.Lock()
+	collection.closed = true
+	refreshers := collection.refreshers
+	collection.refreshers = make(map[string]*refresher)
+	collection.Unlock()
+
+	for _, current := range refreshers {
+		current.cancel()
+	}
+
+	for _, current := range refreshers {
+		<-current.done
+	}
+
+	return nil
+}
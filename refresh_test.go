@@ -0,0 +1,228 @@
+package certdeck_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	"github.com/a-novel-kit/certdeck/clocktest"
+	"github.com/a-novel-kit/certdeck/internal/certs"
+)
+
+// newRefreshTestProvider returns a provider that returns the results in order, then repeats the last one. Every
+// call is reported on the returned channel.
+func newRefreshTestProvider(
+	id string, rows []certdeck.CollectionRow, errs []error,
+) (certdeck.ContextCertsProvider, <-chan int) {
+	calls := make(chan int, 100)
+	count := 0
+
+	return certdeck.NewCertsProviderFunc(id, func(_ context.Context) (certdeck.CollectionRow, error) {
+		pos := min(count, len(rows)-1)
+		count++
+		calls <- count
+
+		return rows[pos], errs[pos]
+	}), calls
+}
+
+func waitForRefresh(t *testing.T, clock *clocktest.Clock) {
+	t.Helper()
+
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+}
+
+func TestCollectionRegister(t *testing.T) {
+	testRow1 := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain1Cert},
+		CertKey: certs.Chain1Key,
+	}
+
+	testRow2 := &certdeck.CollectionRowBase{
+		Certs:   []*x509.Certificate{certs.Chain2Cert},
+		CertKey: certs.Chain2Key,
+	}
+
+	// Far from the expiration of the fixtures.
+	start := certs.Chain1Cert.NotBefore.Add(time.Hour)
+
+	t.Run("refresh", func(t *testing.T) {
+		clock := clocktest.New(start)
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration:   time.Hour,
+			RefreshInterval: 10 * time.Minute,
+			RefreshJitter:   -1,
+			Clock:           clock,
+		})
+		defer collection.Close()
+
+		provider, calls := newRefreshTestProvider(
+			"test-updater", []certdeck.CollectionRow{testRow1, testRow2}, []error{nil, nil},
+		)
+
+		require.NoError(t, collection.Register(provider))
+		// Registering twice does nothing.
+		require.NoError(t, collection.Register(provider))
+
+		// The row is fetched immediately.
+		require.Equal(t, 1, <-calls)
+		waitForRefresh(t, clock)
+
+		data, err := collection.Get(provider)
+		require.NoError(t, err)
+		require.Equal(t, testRow1, data)
+
+		clock.Advance(10 * time.Minute)
+		require.Equal(t, 2, <-calls)
+		waitForRefresh(t, clock)
+
+		data, err = collection.Get(provider)
+		require.NoError(t, err)
+		require.Equal(t, testRow2, data)
+
+		collection.Unregister(provider.ID())
+
+		clock.Advance(10 * time.Minute)
+		require.Empty(t, calls)
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		errFoo := errors.New("foo")
+
+		clock := clocktest.New(start)
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration:   time.Hour,
+			RefreshInterval: 10 * time.Minute,
+			RefreshJitter:   -1,
+			RefreshBackoff:  time.Second,
+			Clock:           clock,
+		})
+		defer collection.Close()
+
+		provider, calls := newRefreshTestProvider(
+			"test-updater",
+			[]certdeck.CollectionRow{nil, nil, nil, testRow1},
+			[]error{errFoo, errFoo, errFoo, nil},
+		)
+
+		require.NoError(t, collection.Register(provider))
+
+		require.Equal(t, 1, <-calls)
+		waitForRefresh(t, clock)
+
+		clock.Advance(time.Second)
+		require.Equal(t, 2, <-calls)
+		waitForRefresh(t, clock)
+
+		// The delay doubles after each failure.
+		clock.Advance(time.Second)
+		require.Empty(t, calls)
+		clock.Advance(time.Second)
+		require.Equal(t, 3, <-calls)
+		waitForRefresh(t, clock)
+
+		clock.Advance(4 * time.Second)
+		require.Equal(t, 4, <-calls)
+		waitForRefresh(t, clock)
+
+		// Success resets the delay to the refresh interval.
+		clock.Advance(10*time.Minute - time.Second)
+		require.Empty(t, calls)
+		clock.Advance(time.Second)
+		require.Equal(t, 5, <-calls)
+	})
+
+	t.Run("refresh before expiry", func(t *testing.T) {
+		clock := clocktest.New(certs.Chain1Cert.NotAfter.Add(-5 * time.Minute))
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration:       time.Hour,
+			RefreshInterval:     time.Hour,
+			RefreshJitter:       -1,
+			RefreshBeforeExpiry: time.Minute,
+			Clock:               clock,
+		})
+		defer collection.Close()
+
+		provider, calls := newRefreshTestProvider(
+			"test-updater", []certdeck.CollectionRow{testRow1}, []error{nil},
+		)
+
+		require.NoError(t, collection.Register(provider))
+		require.Equal(t, 1, <-calls)
+		waitForRefresh(t, clock)
+
+		clock.Advance(4*time.Minute - time.Second)
+		require.Empty(t, calls)
+		clock.Advance(time.Second)
+		require.Equal(t, 2, <-calls)
+	})
+
+	t.Run("ttl hint", func(t *testing.T) {
+		testRow := &certdeck.CollectionRowBase{
+			Certs:   []*x509.Certificate{certs.Chain1Cert},
+			CertKey: certs.Chain1Key,
+			MaxAge:  10 * time.Minute,
+		}
+
+		clock := clocktest.New(start)
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration:   time.Hour,
+			RefreshInterval: 30 * time.Minute,
+			RefreshJitter:   -1,
+			Clock:           clock,
+		})
+		defer collection.Close()
+
+		provider, calls := newRefreshTestProvider(
+			"test-updater", []certdeck.CollectionRow{testRow}, []error{nil},
+		)
+
+		require.NoError(t, collection.Register(provider))
+		require.Equal(t, 1, <-calls)
+		waitForRefresh(t, clock)
+
+		// Rows are refreshed at half their TTL, when it is shorter than the interval.
+		clock.Advance(5*time.Minute - time.Second)
+		require.Empty(t, calls)
+		clock.Advance(time.Second)
+		require.Equal(t, 2, <-calls)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		clock := clocktest.New(start)
+
+		collection := certdeck.NewCollectionWithConfig(&certdeck.CollectionConfig{
+			CacheDuration: time.Hour,
+			Clock:         clock,
+		})
+
+		provider1, calls1 := newRefreshTestProvider(
+			"test-updater-1", []certdeck.CollectionRow{testRow1}, []error{nil},
+		)
+		provider2, calls2 := newRefreshTestProvider(
+			"test-updater-2", []certdeck.CollectionRow{testRow2}, []error{nil},
+		)
+
+		require.NoError(t, collection.Register(provider1))
+		require.NoError(t, collection.Register(provider2))
+		<-calls1
+		<-calls2
+
+		require.NoError(t, collection.Close())
+		require.ErrorIs(t, collection.Register(provider1), certdeck.ErrCollectionClosed)
+
+		// Cached rows can still be read.
+		data, err := collection.Get(provider1)
+		require.NoError(t, err)
+		require.Equal(t, testRow1, data)
+	})
+}
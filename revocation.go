@@ -0,0 +1,118 @@
+package certdeck
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+var (
+	ErrAlreadyRevoked = errors.New("certificate already revoked")
+	ErrNotRevoked     = errors.New("certificate not revoked")
+	ErrNoIssuer       = errors.New("signer has no issuer")
+)
+
+// RevocationReason is a CRL reason code, as defined in RFC 5280, section 5.3.1.
+type RevocationReason int
+
+const (
+	RevocationReasonUnspecified          RevocationReason = 0
+	RevocationReasonKeyCompromise        RevocationReason = 1
+	RevocationReasonCACompromise         RevocationReason = 2
+	RevocationReasonAffiliationChanged   RevocationReason = 3
+	RevocationReasonSuperseded           RevocationReason = 4
+	RevocationReasonCessationOfOperation RevocationReason = 5
+	RevocationReasonCertificateHold      RevocationReason = 6
+	// Value 7 is not used.
+	RevocationReasonRemoveFromCRL      RevocationReason = 8
+	RevocationReasonPrivilegeWithdrawn RevocationReason = 9
+	RevocationReasonAACompromise       RevocationReason = 10
+)
+
+// Revocation describes a revoked certificate.
+type Revocation struct {
+	// Serial is the serial number of the revoked certificate.
+	Serial *big.Int
+	// RevokedAt is the time the certificate was revoked.
+	RevokedAt time.Time
+	// Reason is the reason for the revocation.
+	Reason RevocationReason
+}
+
+// RevocationStore keeps track of revoked serial numbers.
+type RevocationStore interface {
+	// Revoke marks a serial number as revoked. If the serial number is already revoked, this must return
+	// ErrAlreadyRevoked.
+	Revoke(ctx context.Context, revocation *Revocation) error
+	// Get returns the revocation of a serial number. If the serial number is not revoked, this must return
+	// ErrNotRevoked.
+	Get(ctx context.Context, serial *big.Int) (*Revocation, error)
+	// List returns every revoked serial number.
+	List(ctx context.Context) ([]*Revocation, error)
+	// NextCRLNumber returns a new CRL number. Each call must return a number strictly greater than the
+	// previous one.
+	NextCRLNumber(ctx context.Context) (*big.Int, error)
+}
+
+// DefaultCRLValidity is the default interval between the thisUpdate and nextUpdate fields of a CRL.
+const DefaultCRLValidity = 7 * 24 * time.Hour
+
+func (signer *signerImpl) SignCRL(ctx context.Context) (*x509.RevocationList, error) {
+	if signer.revocationStore == nil {
+		return nil, errors.New("signer has no revocation store")
+	}
+
+	signer.RLock()
+	defer signer.RUnlock()
+
+	// Check the issuer first, so no CRL number is wasted.
+	if len(signer.issuers) == 0 {
+		return nil, ErrNoIssuer
+	}
+
+	revocations, err := signer.revocationStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list revocations: %w", err)
+	}
+
+	number, err := signer.revocationStore.NextCRLNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("crl number: %w", err)
+	}
+
+	entries := make([]x509.RevocationListEntry, len(revocations))
+	for pos, revocation := range revocations {
+		entries[pos] = x509.RevocationListEntry{
+			SerialNumber:   revocation.Serial,
+			RevocationTime: revocation.RevokedAt,
+			ReasonCode:     int(revocation.Reason),
+		}
+	}
+
+	now := signer.clock.Now()
+
+	template := &x509.RevocationList{
+		RevokedCertificateEntries: entries,
+		Number:                    number,
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(lo.CoalesceOrEmpty(signer.crlValidity, DefaultCRLValidity)),
+	}
+
+	raw, err := x509.CreateRevocationList(rand.Reader, template, signer.issuers[0], signer.issuerKey)
+	if err != nil {
+		return nil, fmt.Errorf("create revocation list: %w", err)
+	}
+
+	crl, err := x509.ParseRevocationList(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse revocation list: %w", err)
+	}
+
+	return crl, nil
+}
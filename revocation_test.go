@@ -0,0 +1,89 @@
+package certdeck_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	certdeckmocks "github.com/a-novel-kit/certdeck/mocks"
+	"github.com/a-novel-kit/certdeck/stores"
+)
+
+func TestSignerSignCRL(t *testing.T) {
+	serialStore := certdeckmocks.NewMockSerialStore(t)
+	serialStore.On("Insert", context.Background(), mock.Anything).Return(nil)
+
+	revocationStore := stores.NewMemoryRevocationStore()
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rootSigner := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore:     serialStore,
+		RevocationStore: revocationStore,
+	})
+
+	t.Run("no issuer", func(t *testing.T) {
+		_, err := rootSigner.SignCRL(context.Background())
+		require.ErrorIs(t, err, certdeck.ErrNoIssuer)
+	})
+
+	rootCert, err := rootSigner.Sign(
+		context.Background(), rootKey, nil,
+		&certdeck.Template{Exp: time.Hour, Name: pkix.Name{CommonName: "root"}},
+	)
+	require.NoError(t, err)
+
+	signer := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore:     serialStore,
+		RevocationStore: revocationStore,
+		IssuerChain:     []*x509.Certificate{rootCert},
+		IssuerKey:       rootKey,
+		CRLValidity:     time.Hour,
+	})
+
+	revokedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	require.NoError(t, revocationStore.Revoke(context.Background(), &certdeck.Revocation{
+		Serial:    big.NewInt(42),
+		RevokedAt: revokedAt,
+		Reason:    certdeck.RevocationReasonKeyCompromise,
+	}))
+
+	crl, err := signer.SignCRL(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, crl.CheckSignatureFrom(rootCert))
+
+	require.Equal(t, big.NewInt(1), crl.Number)
+	require.WithinDuration(t, crl.ThisUpdate.Add(time.Hour), crl.NextUpdate, time.Second)
+	require.Len(t, crl.RevokedCertificateEntries, 1)
+	require.Equal(t, big.NewInt(42), crl.RevokedCertificateEntries[0].SerialNumber)
+	require.Equal(t, int(certdeck.RevocationReasonKeyCompromise), crl.RevokedCertificateEntries[0].ReasonCode)
+	require.True(t, revokedAt.Equal(crl.RevokedCertificateEntries[0].RevocationTime))
+
+	t.Run("encode", func(t *testing.T) {
+		decoded, err := certdeck.PEMToCRL(certdeck.CRLToPEM(crl))
+		require.NoError(t, err)
+		require.Equal(t, crl.Raw, decoded.Raw)
+
+		decoded, err = certdeck.DERToCRL(certdeck.CRLToDER(crl))
+		require.NoError(t, err)
+		require.Equal(t, crl.Raw, decoded.Raw)
+	})
+
+	t.Run("number increases", func(t *testing.T) {
+		next, err := signer.SignCRL(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, next.Number.Cmp(crl.Number))
+	})
+}
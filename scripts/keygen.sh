@@ -34,3 +34,35 @@ generateCert "2048b-rsa-example" 2048 "cacert" "cakey"
 generateCert "chain-3" 2048 "cacert" "cakey"
 generateCert "chain-2" 2048 "chain-3-cert" "chain-3-keypair"
 generateCert "chain-1" 2048 "chain-2-cert" "chain-2-keypair"
+
+# ======================================================================================================================
+# Encrypted keys, with passphrase "certdeck"
+# ======================================================================================================================
+
+openssl pkcs8 -topk8 -in $BASE_PATH/chain-1-keypair.pem -v2 aes-256-cbc -v2prf hmacWithSHA256 -iter 10000 \
+  -passout pass:certdeck -out $BASE_PATH/encrypted-chain-1-pbkdf2.pem
+openssl pkcs8 -topk8 -in $BASE_PATH/chain-1-keypair.pem -scrypt \
+  -passout pass:certdeck -out $BASE_PATH/encrypted-chain-1-scrypt.pem
+openssl rsa -in $BASE_PATH/chain-1-keypair.pem -aes256 -traditional \
+  -passout pass:certdeck -out $BASE_PATH/encrypted-chain-1-legacy.pem
+
+# ======================================================================================================================
+# PKCS#12 bundles of chain 1, with password "certdeck"
+# ======================================================================================================================
+
+cat $BASE_PATH/chain-1-cert.pem $BASE_PATH/chain-2-cert.pem $BASE_PATH/chain-3-cert.pem > $BASE_PATH/chain.tmp
+openssl pkcs12 -export -inkey $BASE_PATH/chain-1-keypair.pem -in $BASE_PATH/chain.tmp \
+  -passout pass:certdeck -out $BASE_PATH/chain-1-modern.p12
+# RC2 for the certificates, 3DES for the key.
+openssl pkcs12 -export -legacy -inkey $BASE_PATH/chain-1-keypair.pem -in $BASE_PATH/chain.tmp \
+  -passout pass:certdeck -out $BASE_PATH/chain-1-legacy.p12
+rm $BASE_PATH/chain.tmp
+
+# ======================================================================================================================
+# PKCS#7 certs-only bundles of the full chain
+# ======================================================================================================================
+
+openssl crl2pkcs7 -nocrl -certfile $BASE_PATH/chain-1-cert.pem -certfile $BASE_PATH/chain-2-cert.pem \
+  -certfile $BASE_PATH/chain-3-cert.pem -out $BASE_PATH/chain.p7b
+openssl crl2pkcs7 -nocrl -certfile $BASE_PATH/chain-1-cert.pem -certfile $BASE_PATH/chain-2-cert.pem \
+  -certfile $BASE_PATH/chain-3-cert.pem -outform DER -out $BASE_PATH/chain-p7b.der
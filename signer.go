@@ -6,9 +6,11 @@ import (
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
 	"errors"
 	"fmt"
 	"net"
+	"net/url"
 	"sync"
 	"time"
 
@@ -23,6 +25,12 @@ type Template struct {
 	// It is set to 365 days by default.
 	Exp time.Duration
 
+	// NotBefore overrides the start of the validity window of the certificate. It ignores the backdate window
+	// of the signer.
+	NotBefore time.Time
+	// NotAfter overrides the end of the validity window of the certificate. It takes precedence over Exp.
+	NotAfter time.Time
+
 	// Name is the subject of the certificate.
 	Name pkix.Name
 
@@ -32,8 +40,47 @@ type Template struct {
 	// DNSNames is a list of DNS names that the certificate is valid for.
 	DNSNames []string
 
+	// URIs is a list of URIs that the certificate is valid for, for example SPIFFE IDs.
+	URIs []*url.URL
+
+	// EmailAddresses is a list of email addresses that the certificate is valid for.
+	EmailAddresses []string
+
 	// LeafOnly revokes the ability of the issued certificate to sign other certificates.
 	LeafOnly bool
+
+	// KeyUsage sets the key usage of the certificate.
+	//
+	// If zero, it is set to DigitalSignature for leaves, and DigitalSignature | CertSign | CRLSign for
+	// certificate authorities. Leaves cannot have the CertSign usage.
+	KeyUsage x509.KeyUsage
+	// ExtKeyUsage sets the extended key usage of the certificate, for example ExtKeyUsageCodeSigning,
+	// ExtKeyUsageEmailProtection, ExtKeyUsageOCSPSigning or ExtKeyUsageTimeStamping.
+	//
+	// If empty, it is set to ClientAuth and ServerAuth. When the issuer restricts its own extended key usage,
+	// the requested values must be a subset of it.
+	ExtKeyUsage []x509.ExtKeyUsage
+
+	// MaxPathLen limits the number of intermediate certificates that can follow this one in a chain. It only
+	// applies to certificate authorities.
+	//
+	// A zero value means no limit, unless MaxPathLenZero is set. When the issuer has a path length limit, this
+	// value must be strictly lower.
+	MaxPathLen int
+	// MaxPathLenZero forbids this certificate authority from issuing other certificate authorities, when
+	// MaxPathLen is zero.
+	MaxPathLenZero bool
+
+	// NameConstraints restricts the names this certificate authority can issue certificates for. It only
+	// applies to certificate authorities.
+	NameConstraints *NameConstraints
+
+	// PolicyIdentifiers is a list of certificate policy OIDs the certificate was issued under.
+	PolicyIdentifiers []asn1.ObjectIdentifier
+
+	// ExtraExtensions are added to the certificate as-is. They override any extension with the same OID that
+	// would otherwise be generated from the template.
+	ExtraExtensions []pkix.Extension
 }
 
 type Signer interface {
@@ -44,18 +91,41 @@ type Signer interface {
 	//  - *ecdsa.PublicKey
 	//  - ed25519.PublicKey
 	//
-	// KeyID must be a random, unique identifier for the certificate. It can be derived from the public CertKey.
-	// Depending on the type of your public CertKey, you can use any of the provided hashers in this package:
-	//  - HashRSA
-	//  - HashECDSA
-	//  - HashED25519
+	// KeyID must be a random, unique identifier for the certificate. It can be derived from the public CertKey
+	// with KeyID. If nil, it is derived automatically, using the KeyIDMethod of the signer.
 	Sign(ctx context.Context, key any, keyID []byte, template *Template) (*x509.Certificate, error)
+	// SignCSR issues a certificate from a PKCS#10 certificate signing request.
+	//
+	// The signature of the CSR is checked first. The public key of the issued certificate is the one from the CSR,
+	// and the policy decides which of the requested fields are kept, overridden or rejected. A nil policy keeps
+	// every requested field, and issues a leaf certificate.
+	//
+	// Because the signer never holds the private key of the CSR, it must have an issuer chain.
+	SignCSR(ctx context.Context, csr *x509.CertificateRequest, policy *CSRPolicy) (*x509.Certificate, error)
+	// SignCRL builds and signs a certificate revocation list, with every certificate from the revocation store.
+	//
+	// The CRL is signed by the current issuer, so the signer must have an issuer chain.
+	SignCRL(ctx context.Context) (*x509.RevocationList, error)
 	// Rotate updates the issuer chain and the CertKey used to sign the certificates.
 	Rotate(issuers []*x509.Certificate, issuerKey crypto.Signer)
 }
 
 type signerImpl struct {
-	serialStore SerialStore
+	serialStore     SerialStore
+	revocationStore RevocationStore
+
+	crlValidity time.Duration
+
+	ocspServer            []string
+	issuingCertificateURL []string
+	crlDistributionPoints []string
+
+	backdate       time.Duration
+	validityPolicy ValidityPolicy
+
+	keyIDMethod KeyIDMethod
+
+	clock Clock
 
 	issuers   []*x509.Certificate
 	issuerKey crypto.Signer
@@ -71,21 +141,106 @@ func (signer *signerImpl) Rotate(issuers []*x509.Certificate, issuerKey crypto.S
 	signer.issuerKey = issuerKey
 }
 
-func (signer *signerImpl) sign(template *x509.Certificate, key any, leafOnly bool) ([]byte, error) {
+var ErrInvalidTemplate = errors.New("invalid template")
+
+var defaultExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+
+// applyUsage sets the key usages and basic constraints of a certificate, from the template or the defaults.
+func applyUsage(x509Template *x509.Certificate, template *Template, isCA bool) error {
+	x509Template.ExtKeyUsage = lo.Ternary(len(template.ExtKeyUsage) > 0, template.ExtKeyUsage, defaultExtKeyUsage)
+
+	if !isCA {
+		if template.KeyUsage&x509.KeyUsageCertSign != 0 {
+			return fmt.Errorf("%w: leaf certificates cannot have the CertSign key usage", ErrInvalidTemplate)
+		}
+		if template.MaxPathLen != 0 || template.MaxPathLenZero {
+			return fmt.Errorf("%w: path length constraints only apply to certificate authorities", ErrInvalidTemplate)
+		}
+
+		x509Template.KeyUsage = lo.CoalesceOrEmpty(template.KeyUsage, x509.KeyUsageDigitalSignature)
+
+		return nil
+	}
+
+	if template.KeyUsage != 0 && template.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("%w: certificate authorities require the CertSign key usage", ErrInvalidTemplate)
+	}
+	if template.MaxPathLen < 0 {
+		return fmt.Errorf("%w: negative path length", ErrInvalidTemplate)
+	}
+
+	x509Template.KeyUsage = lo.CoalesceOrEmpty(
+		template.KeyUsage,
+		x509.KeyUsageDigitalSignature|x509.KeyUsageCertSign|x509.KeyUsageCRLSign,
+	)
+	x509Template.BasicConstraintsValid = true
+	x509Template.IsCA = true
+	x509Template.MaxPathLen = template.MaxPathLen
+	x509Template.MaxPathLenZero = template.MaxPathLenZero
+
+	return nil
+}
+
+// checkIssuerUsage makes sure the issuer is allowed to sign a certificate with the given usages.
+func checkIssuerUsage(ca *x509.Certificate, x509Template *x509.Certificate) error {
+	if ca.KeyUsage != 0 && ca.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("%w: issuer does not have the CertSign key usage", ErrInvalidTemplate)
+	}
+
+	// A negative MaxPathLen means the issuer has no path length constraint.
+	issuerHasPathLen := ca.MaxPathLen > 0 || (ca.MaxPathLen == 0 && ca.MaxPathLenZero)
+	if x509Template.IsCA && issuerHasPathLen {
+		if ca.MaxPathLen == 0 {
+			return fmt.Errorf("%w: issuer cannot issue certificate authorities", ErrInvalidTemplate)
+		}
+
+		// Unconstrained intermediates inherit the limit of their issuer.
+		if x509Template.MaxPathLen == 0 && !x509Template.MaxPathLenZero {
+			x509Template.MaxPathLen = ca.MaxPathLen - 1
+			x509Template.MaxPathLenZero = x509Template.MaxPathLen == 0
+		}
+
+		if x509Template.MaxPathLen >= ca.MaxPathLen {
+			return fmt.Errorf(
+				"%w: path length %d exceeds the issuer limit of %d",
+				ErrInvalidTemplate, x509Template.MaxPathLen, ca.MaxPathLen-1,
+			)
+		}
+	}
+
+	if len(ca.ExtKeyUsage) > 0 && !lo.Contains(ca.ExtKeyUsage, x509.ExtKeyUsageAny) {
+		for _, usage := range x509Template.ExtKeyUsage {
+			if !lo.Contains(ca.ExtKeyUsage, usage) {
+				return fmt.Errorf("%w: extended key usage %d is not allowed by the issuer", ErrInvalidTemplate, usage)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (signer *signerImpl) sign(template *x509.Certificate, key any) ([]byte, error) {
 	ca := signer.issuers[0]
 	caKey := signer.issuerKey
 
-	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}
+	if err := checkIssuerUsage(ca, template); err != nil {
+		return nil, err
+	}
+
+	if err := checkNameConstraints(signer.issuers, template); err != nil {
+		return nil, err
+	}
+
+	if err := checkIssuerValidity(ca, template, signer.validityPolicy); err != nil {
+		return nil, err
+	}
+
 	template.AuthorityKeyId = ca.SubjectKeyId
 	template.Issuer = ca.Subject
 
-	if !leafOnly {
-		template.BasicConstraintsValid = true
-		template.IsCA = true
-		template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
-	} else {
-		template.KeyUsage = x509.KeyUsageDigitalSignature
-	}
+	template.OCSPServer = signer.ocspServer
+	template.IssuingCertificateURL = signer.issuingCertificateURL
+	template.CRLDistributionPoints = signer.crlDistributionPoints
 
 	raw, err := x509.CreateCertificate(rand.Reader, template, ca, key, caKey)
 	if err != nil {
@@ -96,13 +251,6 @@ func (signer *signerImpl) sign(template *x509.Certificate, key any, leafOnly boo
 }
 
 func (signer *signerImpl) signCA(template *x509.Certificate, key crypto.Signer) ([]byte, error) {
-	template.ExtKeyUsage = []x509.ExtKeyUsage{
-		x509.ExtKeyUsageClientAuth,
-		x509.ExtKeyUsageServerAuth,
-	}
-	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign
-	template.IsCA = true
-	template.BasicConstraintsValid = true
 	template.AuthorityKeyId = template.SubjectKeyId
 
 	raw, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
@@ -113,36 +261,76 @@ func (signer *signerImpl) signCA(template *x509.Certificate, key crypto.Signer)
 	return raw, nil
 }
 
+// deriveKeyID computes the key ID of a certificate, from either its public or private key.
+func deriveKeyID(key any, method KeyIDMethod) ([]byte, error) {
+	if privateKey, ok := key.(crypto.Signer); ok {
+		key = privateKey.Public()
+	}
+
+	return KeyID(key, method)
+}
+
 func (signer *signerImpl) Sign(
 	ctx context.Context, key any, keyID []byte, template *Template,
 ) (*x509.Certificate, error) {
-	const year = 365 * 24 * time.Hour
-
 	serial, err := GenerateSerialWithStore(ctx, signer.serialStore, SerialGenerationMaxRetries)
 	if err != nil {
 		return nil, fmt.Errorf("serial number: %w", err)
 	}
 
+	if keyID == nil {
+		keyID, err = deriveKeyID(key, signer.keyIDMethod)
+		if err != nil {
+			return nil, fmt.Errorf("derive key ID: %w", err)
+		}
+	}
+
 	signer.RLock()
 	defer signer.RUnlock()
 
-	now := time.Now()
-	exp := now.Add(lo.CoalesceOrEmpty(template.Exp, year))
-
 	x509Template := &x509.Certificate{
-		SerialNumber: serial,
-		Subject:      template.Name,
-		IPAddresses:  template.IPAddresses,
-		NotBefore:    now,
-		NotAfter:     exp,
-		SubjectKeyId: keyID,
-		DNSNames:     template.DNSNames,
+		SerialNumber:   serial,
+		Subject:        template.Name,
+		IPAddresses:    template.IPAddresses,
+		SubjectKeyId:   keyID,
+		DNSNames:       template.DNSNames,
+		URIs:           template.URIs,
+		EmailAddresses: template.EmailAddresses,
+
+		PolicyIdentifiers: template.PolicyIdentifiers,
+		ExtraExtensions:   template.ExtraExtensions,
+	}
+
+	// Depending on the x509usepolicies setting, the standard library reads policies from either field.
+	for _, policy := range template.PolicyIdentifiers {
+		oid, err := x509.OIDFromInts(lo.Map(policy, func(item int, _ int) uint64 { return uint64(item) }))
+		if err != nil {
+			return nil, fmt.Errorf("%w: policy identifier %s: %w", ErrInvalidTemplate, policy, err)
+		}
+
+		x509Template.Policies = append(x509Template.Policies, oid)
+	}
+
+	if err = applyValidity(x509Template, template, signer.clock.Now(), signer.backdate); err != nil {
+		return nil, err
 	}
 
 	var raw []byte
 
-	// Self-signed certificate.
-	if len(signer.issuers) == 0 {
+	selfSigned := len(signer.issuers) == 0
+
+	// Self-signed certificates are always certificate authorities.
+	isCA := selfSigned || !template.LeafOnly
+
+	if err = applyUsage(x509Template, template, isCA); err != nil {
+		return nil, err
+	}
+
+	if err = applyNameConstraints(x509Template, template.NameConstraints, isCA); err != nil {
+		return nil, err
+	}
+
+	if selfSigned {
 		privateKey, ok := key.(crypto.Signer)
 		if !ok {
 			return nil, errors.New("self signed CA requires a private key")
@@ -150,7 +338,7 @@ func (signer *signerImpl) Sign(
 
 		raw, err = signer.signCA(x509Template, privateKey)
 	} else {
-		raw, err = signer.sign(x509Template, key, template.LeafOnly)
+		raw, err = signer.sign(x509Template, key)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("sign certificate: %w", err)
@@ -177,12 +365,62 @@ type SignerConfig struct {
 	//  - *ecdsa.PublicKey
 	//  - ed25519.PublicKey
 	IssuerKey crypto.Signer
+
+	// RevocationStore keeps track of revoked certificates. It is only required to sign CRLs.
+	RevocationStore RevocationStore
+	// CRLValidity is the interval between the thisUpdate and nextUpdate fields of signed CRLs.
+	//
+	// DefaultCRLValidity is used by default.
+	CRLValidity time.Duration
+
+	// OCSPServer is a list of OCSP responder URLs, added to the Authority Information Access extension of every
+	// certificate signed by an issuer.
+	OCSPServer []string
+	// IssuingCertificateURL is a list of URLs where the issuer certificate can be downloaded, added to the
+	// Authority Information Access extension of every certificate signed by an issuer.
+	IssuingCertificateURL []string
+	// CRLDistributionPoints is a list of URLs where the CRL of the issuer can be downloaded, added to every
+	// certificate signed by an issuer.
+	CRLDistributionPoints []string
+
+	// Backdate moves the NotBefore of issued certificates in the past, so clients with a slight clock skew
+	// accept them. It does not shorten the lifetime set by Template.Exp.
+	Backdate time.Duration
+	// ValidityPolicy tells what to do when an issued certificate would be valid outside the validity window of
+	// its issuer.
+	//
+	// ValidityClamp is used by default.
+	ValidityPolicy ValidityPolicy
+
+	// KeyIDMethod is used to derive the subject key identifier of issued certificates, when none is provided.
+	//
+	// KeyIDSHA1 is used by default.
+	KeyIDMethod KeyIDMethod
+
+	// Clock provides the current time.
+	//
+	// SystemClock is used by default.
+	Clock Clock
 }
 
 func NewSigner(config *SignerConfig) Signer {
 	return &signerImpl{
-		serialStore: config.SerialStore,
-		issuers:     config.IssuerChain,
-		issuerKey:   config.IssuerKey,
+		serialStore:     config.SerialStore,
+		revocationStore: config.RevocationStore,
+		crlValidity:     config.CRLValidity,
+
+		ocspServer:            config.OCSPServer,
+		issuingCertificateURL: config.IssuingCertificateURL,
+		crlDistributionPoints: config.CRLDistributionPoints,
+
+		backdate:       config.Backdate,
+		validityPolicy: config.ValidityPolicy,
+
+		keyIDMethod: config.KeyIDMethod,
+
+		clock: lo.CoalesceOrEmpty(config.Clock, SystemClock),
+
+		issuers:   config.IssuerChain,
+		issuerKey: config.IssuerKey,
 	}
 }
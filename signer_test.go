@@ -9,6 +9,8 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
 	"testing"
 	"time"
 
@@ -127,3 +129,337 @@ func TestSigner(t *testing.T) {
 
 	store.AssertExpectations(t)
 }
+
+func TestSignerSignCSR(t *testing.T) {
+	store := certdeckmocks.NewMockSerialStore(t)
+	store.On("Insert", context.Background(), mock.Anything).Return(nil)
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rootCert, err := certdeck.NewSigner(&certdeck.SignerConfig{SerialStore: store}).Sign(
+		context.Background(), rootKey, nil,
+		&certdeck.Template{Exp: time.Hour, Name: pkix.Name{CommonName: "root"}},
+	)
+	require.NoError(t, err)
+
+	signer := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore: store,
+		IssuerChain: []*x509.Certificate{rootCert},
+		IssuerKey:   rootKey,
+	})
+
+	csrKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	csrRaw, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:        pkix.Name{CommonName: "service"},
+		DNSNames:       []string{"service.local"},
+		IPAddresses:    certdeck.IPLocalHost,
+		EmailAddresses: []string{"service@local"},
+	}, csrKey)
+	require.NoError(t, err)
+
+	csr, err := certdeck.DERToCSR(csrRaw)
+	require.NoError(t, err)
+
+	t.Run("keep", func(t *testing.T) {
+		cert, err := signer.SignCSR(context.Background(), csr, &certdeck.CSRPolicy{
+			Template: &certdeck.Template{Exp: time.Minute, LeafOnly: true},
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "service", cert.Subject.CommonName)
+		require.Equal(t, []string{"service.local"}, cert.DNSNames)
+		require.Len(t, cert.IPAddresses, 2)
+		require.Equal(t, []string{"service@local"}, cert.EmailAddresses)
+		require.True(t, csrKey.PublicKey.Equal(cert.PublicKey))
+		require.False(t, cert.IsCA)
+
+		roots := x509.NewCertPool()
+		roots.AddCert(rootCert)
+
+		_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "service.local"})
+		require.NoError(t, err)
+	})
+
+	t.Run("nil policy", func(t *testing.T) {
+		cert, err := signer.SignCSR(context.Background(), csr, nil)
+		require.NoError(t, err)
+
+		// CSRs never get a certificate authority, unless the policy allows it.
+		require.False(t, cert.IsCA)
+		require.Zero(t, cert.KeyUsage&x509.KeyUsageCertSign)
+		require.Equal(t, "service", cert.Subject.CommonName)
+	})
+
+	t.Run("allow CA", func(t *testing.T) {
+		cert, err := signer.SignCSR(context.Background(), csr, &certdeck.CSRPolicy{
+			Template: &certdeck.Template{Exp: time.Minute},
+		})
+		require.NoError(t, err)
+		require.False(t, cert.IsCA)
+
+		cert, err = signer.SignCSR(context.Background(), csr, &certdeck.CSRPolicy{
+			Template: &certdeck.Template{Exp: time.Minute},
+			AllowCA:  true,
+		})
+		require.NoError(t, err)
+		require.True(t, cert.IsCA)
+	})
+
+	t.Run("override", func(t *testing.T) {
+		cert, err := signer.SignCSR(context.Background(), csr, &certdeck.CSRPolicy{
+			Template: &certdeck.Template{
+				Exp:      time.Minute,
+				Name:     pkix.Name{CommonName: "overridden"},
+				DNSNames: []string{"other.local"},
+				LeafOnly: true,
+			},
+			Subject:        certdeck.CSRFieldOverride,
+			DNSNames:       certdeck.CSRFieldOverride,
+			IPAddresses:    certdeck.CSRFieldOverride,
+			EmailAddresses: certdeck.CSRFieldOverride,
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "overridden", cert.Subject.CommonName)
+		require.Equal(t, []string{"other.local"}, cert.DNSNames)
+		require.Empty(t, cert.IPAddresses)
+		require.Empty(t, cert.EmailAddresses)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		_, err := signer.SignCSR(context.Background(), csr, &certdeck.CSRPolicy{
+			IPAddresses: certdeck.CSRFieldReject,
+		})
+		require.ErrorIs(t, err, certdeck.ErrCSRRejected)
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		_, err := signer.SignCSR(context.Background(), csr, &certdeck.CSRPolicy{
+			Validate: func(_ *x509.CertificateRequest, _ *certdeck.Template) error {
+				return errors.New("nope")
+			},
+		})
+		require.ErrorIs(t, err, certdeck.ErrCSRRejected)
+	})
+
+	t.Run("bad signature", func(t *testing.T) {
+		tampered := *csr
+		tampered.Signature = append([]byte{}, csr.Signature...)
+		tampered.Signature[len(tampered.Signature)-1] ^= 0xff
+
+		_, err := signer.SignCSR(context.Background(), &tampered, nil)
+		require.ErrorIs(t, err, certdeck.ErrInvalidCSR)
+	})
+}
+
+func TestSignerUsage(t *testing.T) {
+	store := certdeckmocks.NewMockSerialStore(t)
+	store.On("Insert", context.Background(), mock.Anything).Return(nil)
+
+	newKey := func(t *testing.T) *ecdsa.PrivateKey {
+		t.Helper()
+
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		return key
+	}
+
+	rootKey := newKey(t)
+	rootCert, err := certdeck.NewSigner(&certdeck.SignerConfig{SerialStore: store}).Sign(
+		context.Background(), rootKey, nil,
+		&certdeck.Template{Exp: time.Hour, MaxPathLen: 1, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, rootCert.MaxPathLen)
+
+	rootSigner := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore: store,
+		IssuerChain: []*x509.Certificate{rootCert},
+		IssuerKey:   rootKey,
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		key := newKey(t)
+
+		cert, err := rootSigner.Sign(
+			context.Background(), key.Public(), nil,
+			&certdeck.Template{Exp: time.Hour, LeafOnly: true},
+		)
+		require.NoError(t, err)
+		require.Equal(t, x509.KeyUsageDigitalSignature, cert.KeyUsage)
+		require.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth}, cert.ExtKeyUsage)
+		require.False(t, cert.IsCA)
+	})
+
+	t.Run("custom leaf usage", func(t *testing.T) {
+		key := newKey(t)
+
+		cert, err := rootSigner.Sign(
+			context.Background(), key.Public(), nil,
+			&certdeck.Template{
+				Exp:         time.Hour,
+				LeafOnly:    true,
+				KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning, x509.ExtKeyUsageTimeStamping},
+			},
+		)
+		require.NoError(t, err)
+		require.Equal(t, x509.KeyUsageDigitalSignature|x509.KeyUsageContentCommitment, cert.KeyUsage)
+		require.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning, x509.ExtKeyUsageTimeStamping}, cert.ExtKeyUsage)
+	})
+
+	t.Run("intermediate inherits path length", func(t *testing.T) {
+		key := newKey(t)
+
+		cert, err := rootSigner.Sign(
+			context.Background(), key.Public(), nil,
+			&certdeck.Template{
+				Exp:         time.Hour,
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+			},
+		)
+		require.NoError(t, err)
+		require.True(t, cert.IsCA)
+		require.Equal(t, 0, cert.MaxPathLen)
+		require.True(t, cert.MaxPathLenZero)
+
+		intermediateSigner := certdeck.NewSigner(&certdeck.SignerConfig{
+			SerialStore: store,
+			IssuerChain: []*x509.Certificate{cert, rootCert},
+			IssuerKey:   key,
+		})
+
+		subKey := newKey(t)
+
+		t.Run("cannot issue CA", func(t *testing.T) {
+			_, err := intermediateSigner.Sign(
+				context.Background(), subKey.Public(), nil,
+				&certdeck.Template{Exp: time.Hour},
+			)
+			require.ErrorIs(t, err, certdeck.ErrInvalidTemplate)
+		})
+
+		t.Run("extended key usage not allowed", func(t *testing.T) {
+			_, err := intermediateSigner.Sign(
+				context.Background(), subKey.Public(), nil,
+				&certdeck.Template{Exp: time.Hour, LeafOnly: true},
+			)
+			require.ErrorIs(t, err, certdeck.ErrInvalidTemplate)
+		})
+
+		t.Run("extended key usage allowed", func(t *testing.T) {
+			_, err := intermediateSigner.Sign(
+				context.Background(), subKey.Public(), nil,
+				&certdeck.Template{
+					Exp:         time.Hour,
+					LeafOnly:    true,
+					ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+				},
+			)
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("path length exceeds issuer", func(t *testing.T) {
+		key := newKey(t)
+
+		_, err := rootSigner.Sign(
+			context.Background(), key.Public(), nil,
+			&certdeck.Template{Exp: time.Hour, MaxPathLen: 1},
+		)
+		require.ErrorIs(t, err, certdeck.ErrInvalidTemplate)
+	})
+
+	t.Run("leaf with cert sign", func(t *testing.T) {
+		key := newKey(t)
+
+		_, err := rootSigner.Sign(
+			context.Background(), key.Public(), nil,
+			&certdeck.Template{Exp: time.Hour, LeafOnly: true, KeyUsage: x509.KeyUsageCertSign},
+		)
+		require.ErrorIs(t, err, certdeck.ErrInvalidTemplate)
+	})
+
+	t.Run("leaf with path length", func(t *testing.T) {
+		key := newKey(t)
+
+		_, err := rootSigner.Sign(
+			context.Background(), key.Public(), nil,
+			&certdeck.Template{Exp: time.Hour, LeafOnly: true, MaxPathLenZero: true},
+		)
+		require.ErrorIs(t, err, certdeck.ErrInvalidTemplate)
+	})
+
+	t.Run("CA without cert sign", func(t *testing.T) {
+		key := newKey(t)
+
+		_, err := rootSigner.Sign(
+			context.Background(), key.Public(), nil,
+			&certdeck.Template{Exp: time.Hour, KeyUsage: x509.KeyUsageDigitalSignature},
+		)
+		require.ErrorIs(t, err, certdeck.ErrInvalidTemplate)
+	})
+}
+
+func TestSignerExtensions(t *testing.T) {
+	store := certdeckmocks.NewMockSerialStore(t)
+	store.On("Insert", context.Background(), mock.Anything).Return(nil)
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rootCert, err := certdeck.NewSigner(&certdeck.SignerConfig{SerialStore: store}).Sign(
+		context.Background(), rootKey, nil,
+		&certdeck.Template{Exp: time.Hour, Name: pkix.Name{CommonName: "root"}},
+	)
+	require.NoError(t, err)
+	require.Empty(t, rootCert.OCSPServer)
+
+	signer := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore:           store,
+		IssuerChain:           []*x509.Certificate{rootCert},
+		IssuerKey:             rootKey,
+		OCSPServer:            []string{"http://ocsp.example.com"},
+		IssuingCertificateURL: []string{"http://pki.example.com/root.crt"},
+		CRLDistributionPoints: []string{"http://pki.example.com/root.crl"},
+	})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	policyOID := asn1.ObjectIdentifier{2, 23, 140, 1, 2, 1}
+	customOID := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}
+
+	cert, err := signer.Sign(
+		context.Background(), key.Public(), nil,
+		&certdeck.Template{
+			Exp:               time.Hour,
+			LeafOnly:          true,
+			PolicyIdentifiers: []asn1.ObjectIdentifier{policyOID},
+			ExtraExtensions: []pkix.Extension{
+				{Id: customOID, Value: []byte{0x05, 0x00}},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"http://ocsp.example.com"}, cert.OCSPServer)
+	require.Equal(t, []string{"http://pki.example.com/root.crt"}, cert.IssuingCertificateURL)
+	require.Equal(t, []string{"http://pki.example.com/root.crl"}, cert.CRLDistributionPoints)
+
+	require.Len(t, cert.PolicyIdentifiers, 1)
+	require.True(t, policyOID.Equal(cert.PolicyIdentifiers[0]))
+
+	var found bool
+	for _, extension := range cert.Extensions {
+		if extension.Id.Equal(customOID) {
+			found = true
+			require.Equal(t, []byte{0x05, 0x00}, extension.Value)
+		}
+	}
+	require.True(t, found)
+}
@@ -0,0 +1,94 @@
+package certdeck
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSPIFFEID = errors.New("invalid SPIFFE ID")
+
+// SPIFFEIDMaxLength is the maximum length of a SPIFFE ID, in bytes.
+const SPIFFEIDMaxLength = 2048
+
+func isSPIFFETrustDomainChar(char rune) bool {
+	return (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') ||
+		char == '.' || char == '-' || char == '_'
+}
+
+func isSPIFFEPathChar(char rune) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') ||
+		char == '.' || char == '-' || char == '_'
+}
+
+// ParseSPIFFEID parses a SPIFFE ID, of the form spiffe://trust-domain/path, and checks it follows the format
+// defined by the SPIFFE ID specification.
+func ParseSPIFFEID(id string) (*url.URL, error) {
+	if len(id) > SPIFFEIDMaxLength {
+		return nil, fmt.Errorf("%w: longer than %d bytes", ErrInvalidSPIFFEID, SPIFFEIDMaxLength)
+	}
+
+	rest, ok := strings.CutPrefix(id, "spiffe://")
+	if !ok {
+		return nil, fmt.Errorf("%w: scheme must be spiffe", ErrInvalidSPIFFEID)
+	}
+
+	trustDomain, path, _ := strings.Cut(rest, "/")
+	if trustDomain == "" {
+		return nil, fmt.Errorf("%w: missing trust domain", ErrInvalidSPIFFEID)
+	}
+
+	for _, char := range trustDomain {
+		if !isSPIFFETrustDomainChar(char) {
+			return nil, fmt.Errorf("%w: invalid character %q in trust domain", ErrInvalidSPIFFEID, char)
+		}
+	}
+
+	if path != "" || strings.HasSuffix(rest, "/") {
+		for _, segment := range strings.Split(path, "/") {
+			if segment == "" {
+				return nil, fmt.Errorf("%w: empty path segment", ErrInvalidSPIFFEID)
+			}
+			if segment == "." || segment == ".." {
+				return nil, fmt.Errorf("%w: relative path segment", ErrInvalidSPIFFEID)
+			}
+
+			for _, char := range segment {
+				if !isSPIFFEPathChar(char) {
+					return nil, fmt.Errorf("%w: invalid character %q in path", ErrInvalidSPIFFEID, char)
+				}
+			}
+		}
+	}
+
+	// Characters are already restricted, so the URL parser cannot find a port, user info, query or fragment.
+	parsed, err := url.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSPIFFEID, err)
+	}
+
+	return parsed, nil
+}
+
+// SPIFFETemplate returns a template for an X.509 SVID, the leaf certificate that identifies a workload in
+// SPIFFE.
+//
+// The certificate has the SPIFFE ID as its only subject alternative name, and an empty subject. Because the
+// subject is empty, the subject alternative name extension is marked critical. You can still set a subject on
+// the returned template.
+func SPIFFETemplate(id string, exp time.Duration) (*Template, error) {
+	parsed, err := ParseSPIFFEID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{
+		Exp:      exp,
+		URIs:     []*url.URL{parsed},
+		LeafOnly: true,
+		KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement,
+	}, nil
+}
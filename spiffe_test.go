@@ -0,0 +1,148 @@
+package certdeck_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	certdeckmocks "github.com/a-novel-kit/certdeck/mocks"
+)
+
+func TestParseSPIFFEID(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		id string
+
+		expect error
+	}{
+		{
+			name: "valid",
+
+			id: "spiffe://example.org/ns/default/sa/api",
+		},
+		{
+			name: "trust domain only",
+
+			id: "spiffe://example.org",
+		},
+		{
+			name: "wrong scheme",
+
+			id:     "https://example.org/api",
+			expect: certdeck.ErrInvalidSPIFFEID,
+		},
+		{
+			name: "uppercase trust domain",
+
+			id:     "spiffe://Example.org/api",
+			expect: certdeck.ErrInvalidSPIFFEID,
+		},
+		{
+			name: "port",
+
+			id:     "spiffe://example.org:8080/api",
+			expect: certdeck.ErrInvalidSPIFFEID,
+		},
+		{
+			name: "trailing slash",
+
+			id:     "spiffe://example.org/api/",
+			expect: certdeck.ErrInvalidSPIFFEID,
+		},
+		{
+			name: "relative segment",
+
+			id:     "spiffe://example.org/api/../admin",
+			expect: certdeck.ErrInvalidSPIFFEID,
+		},
+		{
+			name: "query",
+
+			id:     "spiffe://example.org/api?foo=bar",
+			expect: certdeck.ErrInvalidSPIFFEID,
+		},
+		{
+			name: "missing trust domain",
+
+			id:     "spiffe:///api",
+			expect: certdeck.ErrInvalidSPIFFEID,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			parsed, err := certdeck.ParseSPIFFEID(testCase.id)
+			require.ErrorIs(t, err, testCase.expect)
+
+			if testCase.expect == nil {
+				require.Equal(t, testCase.id, parsed.String())
+			}
+		})
+	}
+}
+
+func TestSignerSPIFFE(t *testing.T) {
+	store := certdeckmocks.NewMockSerialStore(t)
+	store.On("Insert", context.Background(), mock.Anything).Return(nil)
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rootCert, err := certdeck.NewSigner(&certdeck.SignerConfig{SerialStore: store}).Sign(
+		context.Background(), rootKey, nil,
+		&certdeck.Template{Exp: time.Hour, Name: pkix.Name{CommonName: "root"}},
+	)
+	require.NoError(t, err)
+
+	signer := certdeck.NewSigner(&certdeck.SignerConfig{
+		SerialStore: store,
+		IssuerChain: []*x509.Certificate{rootCert},
+		IssuerKey:   rootKey,
+	})
+
+	template, err := certdeck.SPIFFETemplate("spiffe://example.org/ns/default/sa/api", time.Hour)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	cert, err := signer.Sign(context.Background(), key.Public(), nil, template)
+	require.NoError(t, err)
+
+	require.Empty(t, cert.Subject.ToRDNSequence())
+	require.Empty(t, cert.DNSNames)
+	require.Empty(t, cert.IPAddresses)
+	require.Len(t, cert.URIs, 1)
+	require.Equal(t, "spiffe://example.org/ns/default/sa/api", cert.URIs[0].String())
+	require.False(t, cert.IsCA)
+
+	oidSAN := asn1.ObjectIdentifier{2, 5, 29, 17}
+
+	var sanCritical bool
+	for _, extension := range cert.Extensions {
+		if extension.Id.Equal(oidSAN) {
+			sanCritical = extension.Critical
+		}
+	}
+	require.True(t, sanCritical)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(rootCert)
+
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots})
+	require.NoError(t, err)
+
+	_, err = certdeck.SPIFFETemplate("spiffe://example.org/api/", time.Hour)
+	require.ErrorIs(t, err, certdeck.ErrInvalidSPIFFEID)
+}
@@ -3,6 +3,8 @@ package stores
 import (
 	"context"
 	"math/big"
+	"sort"
+	"sync"
 
 	"github.com/a-novel-kit/certdeck"
 )
@@ -29,3 +31,72 @@ func (m *MemoryStore) Insert(_ context.Context, serial *big.Int) error {
 func NewMemoryStore() *MemoryStore {
 	return &MemoryStore{}
 }
+
+type MemoryRevocationStore struct {
+	revocations map[string]*certdeck.Revocation
+	crlNumber   *big.Int
+
+	mu sync.RWMutex
+}
+
+func (m *MemoryRevocationStore) Revoke(_ context.Context, revocation *certdeck.Revocation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.revocations == nil {
+		m.revocations = make(map[string]*certdeck.Revocation)
+	}
+
+	serialStr := revocation.Serial.String()
+	if _, ok := m.revocations[serialStr]; ok {
+		return certdeck.ErrAlreadyRevoked
+	}
+
+	m.revocations[serialStr] = revocation
+	return nil
+}
+
+func (m *MemoryRevocationStore) Get(_ context.Context, serial *big.Int) (*certdeck.Revocation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revocation, ok := m.revocations[serial.String()]
+	if !ok {
+		return nil, certdeck.ErrNotRevoked
+	}
+
+	return revocation, nil
+}
+
+func (m *MemoryRevocationStore) List(_ context.Context) ([]*certdeck.Revocation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	revocations := make([]*certdeck.Revocation, 0, len(m.revocations))
+	for _, revocation := range m.revocations {
+		revocations = append(revocations, revocation)
+	}
+
+	// Keep a stable order, so CRLs are reproducible.
+	sort.Slice(revocations, func(i, j int) bool {
+		return revocations[i].Serial.Cmp(revocations[j].Serial) < 0
+	})
+
+	return revocations, nil
+}
+
+func (m *MemoryRevocationStore) NextCRLNumber(_ context.Context) (*big.Int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.crlNumber == nil {
+		m.crlNumber = new(big.Int)
+	}
+
+	m.crlNumber = new(big.Int).Add(m.crlNumber, big.NewInt(1))
+	return new(big.Int).Set(m.crlNumber), nil
+}
+
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -23,3 +24,36 @@ func TestMemoryStore(t *testing.T) {
 
 	require.ErrorIs(t, store.Insert(context.Background(), int1), certdeck.ErrAlreadyExists)
 }
+
+func TestMemoryRevocationStore(t *testing.T) {
+	store := stores.NewMemoryRevocationStore()
+	require.NotNil(t, store)
+
+	revocation1 := &certdeck.Revocation{Serial: big.NewInt(2), RevokedAt: time.Now()}
+	revocation2 := &certdeck.Revocation{
+		Serial:    big.NewInt(1),
+		RevokedAt: time.Now(),
+		Reason:    certdeck.RevocationReasonKeyCompromise,
+	}
+
+	_, err := store.Get(context.Background(), revocation1.Serial)
+	require.ErrorIs(t, err, certdeck.ErrNotRevoked)
+
+	require.NoError(t, store.Revoke(context.Background(), revocation1))
+	require.NoError(t, store.Revoke(context.Background(), revocation2))
+	require.ErrorIs(t, store.Revoke(context.Background(), revocation1), certdeck.ErrAlreadyRevoked)
+
+	got, err := store.Get(context.Background(), big.NewInt(1))
+	require.NoError(t, err)
+	require.Equal(t, revocation2, got)
+
+	list, err := store.List(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []*certdeck.Revocation{revocation2, revocation1}, list)
+
+	number1, err := store.NextCRLNumber(context.Background())
+	require.NoError(t, err)
+	number2, err := store.NextCRLNumber(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, number2.Cmp(number1))
+}
@@ -0,0 +1,83 @@
+package certdeck
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrOutlivesIssuer = errors.New("certificate validity exceeds the issuer validity")
+
+// ValidityPolicy tells the signer what to do when a certificate would be valid outside the validity window of
+// its issuer.
+type ValidityPolicy int
+
+const (
+	// ValidityClamp shortens the validity of the certificate, so it fits within the validity of its issuer.
+	ValidityClamp ValidityPolicy = iota
+	// ValidityReject refuses to issue a certificate that expires after its issuer. A NotBefore earlier than the
+	// issuer one is still clamped, so backdating works right after the issuer is rotated.
+	ValidityReject
+	// ValidityAllow issues the certificate as requested. Clients will reject the chain when the issuer expires.
+	ValidityAllow
+)
+
+// applyValidity sets the validity window of a certificate, from the template and the signer configuration.
+func applyValidity(x509Template *x509.Certificate, template *Template, now time.Time, backdate time.Duration) error {
+	const year = 365 * 24 * time.Hour
+
+	x509Template.NotBefore = now.Add(-backdate)
+	if !template.NotBefore.IsZero() {
+		x509Template.NotBefore = template.NotBefore
+	}
+
+	// Expiration is computed from the current time, so backdating does not shorten the certificate lifetime.
+	x509Template.NotAfter = now.Add(year)
+	if template.Exp > 0 {
+		x509Template.NotAfter = now.Add(template.Exp)
+	}
+	if !template.NotAfter.IsZero() {
+		x509Template.NotAfter = template.NotAfter
+	}
+
+	if !x509Template.NotAfter.After(x509Template.NotBefore) {
+		return fmt.Errorf("%w: NotAfter must be after NotBefore", ErrInvalidTemplate)
+	}
+
+	return nil
+}
+
+// checkIssuerValidity makes sure a certificate is not valid outside the validity window of its issuer.
+//
+// A NotBefore earlier than the issuer NotBefore is always clamped, unless the policy is ValidityAllow. This
+// happens when backdating right after the issuer is rotated, and does not extend the certificate lifetime.
+func checkIssuerValidity(ca *x509.Certificate, x509Template *x509.Certificate, policy ValidityPolicy) error {
+	switch policy {
+	case ValidityAllow:
+		return nil
+	case ValidityReject:
+		if x509Template.NotAfter.After(ca.NotAfter) {
+			return fmt.Errorf(
+				"%w: NotAfter %s is after the issuer NotAfter %s",
+				ErrOutlivesIssuer, x509Template.NotAfter, ca.NotAfter,
+			)
+		}
+	case ValidityClamp:
+		if x509Template.NotAfter.After(ca.NotAfter) {
+			x509Template.NotAfter = ca.NotAfter
+		}
+	default:
+		return fmt.Errorf("unknown validity policy %d", policy)
+	}
+
+	if x509Template.NotBefore.Before(ca.NotBefore) {
+		x509Template.NotBefore = ca.NotBefore
+	}
+
+	if !x509Template.NotAfter.After(x509Template.NotBefore) {
+		return fmt.Errorf("%w: no overlap with the issuer validity", ErrOutlivesIssuer)
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package certdeck_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	"github.com/a-novel-kit/certdeck/clocktest"
+	certdeckmocks "github.com/a-novel-kit/certdeck/mocks"
+)
+
+func TestSignerValidity(t *testing.T) {
+	store := certdeckmocks.NewMockSerialStore(t)
+	store.On("Insert", context.Background(), mock.Anything).Return(nil)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := clocktest.New(now)
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rootCert, err := certdeck.NewSigner(&certdeck.SignerConfig{SerialStore: store, Clock: clock}).Sign(
+		context.Background(), rootKey, nil,
+		&certdeck.Template{Exp: 24 * time.Hour},
+	)
+	require.NoError(t, err)
+	require.Equal(t, now, rootCert.NotBefore)
+	require.Equal(t, now.Add(24*time.Hour), rootCert.NotAfter)
+
+	testCases := []struct {
+		name string
+
+		config   *certdeck.SignerConfig
+		template *certdeck.Template
+
+		expectNotBefore time.Time
+		expectNotAfter  time.Time
+		expect          error
+	}{
+		{
+			name: "default",
+
+			config:   &certdeck.SignerConfig{},
+			template: &certdeck.Template{Exp: time.Hour},
+
+			expectNotBefore: now,
+			expectNotAfter:  now.Add(time.Hour),
+		},
+		{
+			name: "backdate",
+
+			config:   &certdeck.SignerConfig{Backdate: 5 * time.Minute, ValidityPolicy: certdeck.ValidityAllow},
+			template: &certdeck.Template{Exp: time.Hour},
+
+			expectNotBefore: now.Add(-5 * time.Minute),
+			expectNotAfter:  now.Add(time.Hour),
+		},
+		{
+			name: "backdate clamped to issuer",
+
+			config:   &certdeck.SignerConfig{Backdate: 5 * time.Minute},
+			template: &certdeck.Template{Exp: time.Hour},
+
+			expectNotBefore: now,
+			expectNotAfter:  now.Add(time.Hour),
+		},
+		{
+			name: "clamp",
+
+			config:   &certdeck.SignerConfig{},
+			template: &certdeck.Template{Exp: 48 * time.Hour},
+
+			expectNotBefore: now,
+			expectNotAfter:  now.Add(24 * time.Hour),
+		},
+		{
+			name: "reject",
+
+			config:   &certdeck.SignerConfig{ValidityPolicy: certdeck.ValidityReject},
+			template: &certdeck.Template{Exp: 48 * time.Hour},
+
+			expect: certdeck.ErrOutlivesIssuer,
+		},
+		{
+			name: "backdate with reject",
+
+			config: &certdeck.SignerConfig{
+				Backdate:       5 * time.Minute,
+				ValidityPolicy: certdeck.ValidityReject,
+			},
+			template: &certdeck.Template{Exp: time.Hour},
+
+			expectNotBefore: now,
+			expectNotAfter:  now.Add(time.Hour),
+		},
+		{
+			name: "reject before issuer",
+
+			config: &certdeck.SignerConfig{ValidityPolicy: certdeck.ValidityReject},
+			template: &certdeck.Template{
+				NotBefore: now.Add(-2 * time.Hour),
+				NotAfter:  now.Add(-time.Hour),
+			},
+
+			expect: certdeck.ErrOutlivesIssuer,
+		},
+		{
+			name: "allow",
+
+			config:   &certdeck.SignerConfig{ValidityPolicy: certdeck.ValidityAllow},
+			template: &certdeck.Template{Exp: 48 * time.Hour},
+
+			expectNotBefore: now,
+			expectNotAfter:  now.Add(48 * time.Hour),
+		},
+		{
+			name: "explicit window",
+
+			config: &certdeck.SignerConfig{Backdate: time.Hour},
+			template: &certdeck.Template{
+				Exp:       time.Minute,
+				NotBefore: now.Add(time.Hour),
+				NotAfter:  now.Add(2 * time.Hour),
+			},
+
+			expectNotBefore: now.Add(time.Hour),
+			expectNotAfter:  now.Add(2 * time.Hour),
+		},
+		{
+			name: "invalid window",
+
+			config: &certdeck.SignerConfig{},
+			template: &certdeck.Template{
+				NotBefore: now.Add(time.Hour),
+				NotAfter:  now,
+			},
+
+			expect: certdeck.ErrInvalidTemplate,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.config.SerialStore = store
+			testCase.config.IssuerChain = []*x509.Certificate{rootCert}
+			testCase.config.IssuerKey = rootKey
+			testCase.config.Clock = clock
+
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			require.NoError(t, err)
+
+			testCase.template.LeafOnly = true
+
+			cert, err := certdeck.NewSigner(testCase.config).Sign(
+				context.Background(), key.Public(), nil, testCase.template,
+			)
+			require.ErrorIs(t, err, testCase.expect)
+
+			if testCase.expect != nil {
+				return
+			}
+
+			require.Equal(t, testCase.expectNotBefore, cert.NotBefore)
+			require.Equal(t, testCase.expectNotAfter, cert.NotAfter)
+		})
+	}
+}
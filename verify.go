@@ -0,0 +1,256 @@
+package certdeck
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+var (
+	ErrEmptyChain       = errors.New("empty certificate chain")
+	ErrBrokenLink       = errors.New("certificate is not issued by the next certificate of the chain")
+	ErrUntrustedRoot    = errors.New("chain does not end with a trusted root")
+	ErrCertExpired      = errors.New("certificate has expired")
+	ErrCertNotYetValid  = errors.New("certificate is not yet valid")
+	ErrNotCA            = errors.New("issuer is not a certificate authority")
+	ErrPathLength       = errors.New("issuer path length exceeded")
+	ErrKeyUsage         = errors.New("certificate key usage does not allow this use")
+	ErrHostnameMismatch = errors.New("certificate is not valid for this host")
+)
+
+// VerifyOptions configures VerifyRow.
+type VerifyOptions struct {
+	// Roots are the trusted certificate authorities. The chain must either contain one of them, or end with a
+	// certificate issued by one of them. A chain can never be valid without roots.
+	Roots []*x509.Certificate
+
+	// Hostname is the DNS name or IP address the leaf must be valid for. It is not checked if empty.
+	Hostname string
+
+	// ExtKeyUsage lists the accepted extended key usages. Every certificate of the chain that restricts its
+	// extended key usage must allow at least one of them. Use x509.ExtKeyUsageAny to skip this check.
+	//
+	// x509.ExtKeyUsageServerAuth is used by default.
+	ExtKeyUsage []x509.ExtKeyUsage
+
+	// Clock provides the time at which the validity windows are checked.
+	//
+	// SystemClock is used by default.
+	Clock Clock
+}
+
+// VerifyFailure is a single failed check of VerifyRow.
+type VerifyFailure struct {
+	// Index is the position of the certificate in VerifyReport.Chain, 0 being the leaf. It is -1 when the failure
+	// is not related to a certificate.
+	Index int
+	// Certificate is the certificate that failed the check, if any. For broken links, this is the child
+	// certificate.
+	Certificate *x509.Certificate
+	// Err wraps one of the verification errors, like ErrCertExpired or ErrNameConstraint, with details.
+	Err error
+}
+
+func (failure *VerifyFailure) Error() string {
+	if failure.Certificate == nil {
+		return failure.Err.Error()
+	}
+
+	return fmt.Sprintf("certificate %d (%s): %s", failure.Index, failure.Certificate.Subject, failure.Err)
+}
+
+func (failure *VerifyFailure) Unwrap() error {
+	return failure.Err
+}
+
+// VerifyReport is the result of VerifyRow.
+type VerifyReport struct {
+	// Chain is the verified chain, leaf first. It ends with the trusted root when one was found, which may not
+	// be part of the row.
+	Chain []*x509.Certificate
+	// Root is the trusted root of the chain, if any.
+	Root *x509.Certificate
+	// Failures lists every failed check.
+	Failures []*VerifyFailure
+}
+
+// Valid reports whether every check succeeded.
+func (report *VerifyReport) Valid() bool {
+	return len(report.Failures) == 0
+}
+
+// Err returns the failures joined in a single error, or nil if the chain is valid.
+func (report *VerifyReport) Err() error {
+	errs := make([]error, len(report.Failures))
+	for pos, failure := range report.Failures {
+		errs[pos] = failure
+	}
+
+	return errors.Join(errs...)
+}
+
+func (report *VerifyReport) fail(index int, err error) {
+	failure := &VerifyFailure{Index: index, Err: err}
+	if index >= 0 {
+		failure.Certificate = report.Chain[index]
+	}
+
+	report.Failures = append(report.Failures, failure)
+}
+
+func containsCert(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	return slices.ContainsFunc(certs, cert.Equal)
+}
+
+// anchor truncates the chain after its first trusted certificate, or appends the root that issued its last
+// certificate.
+func (report *VerifyReport) anchor(roots []*x509.Certificate) bool {
+	for pos, cert := range report.Chain {
+		if containsCert(roots, cert) {
+			report.Chain = report.Chain[:pos+1]
+			report.Root = cert
+
+			return true
+		}
+	}
+
+	last := report.Chain[len(report.Chain)-1]
+	for _, root := range roots {
+		if isIssuer(last, root) {
+			report.Chain = append(report.Chain, root)
+			report.Root = root
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkValidity(cert *x509.Certificate, now time.Time) error {
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("%w: valid from %s", ErrCertNotYetValid, cert.NotBefore)
+	}
+
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("%w: valid until %s", ErrCertExpired, cert.NotAfter)
+	}
+
+	return nil
+}
+
+// checkIssuer checks the basic constraints and key usage of the issuer at position index of the chain.
+func checkIssuer(issuer *x509.Certificate, index int) error {
+	if !issuer.BasicConstraintsValid || !issuer.IsCA {
+		return ErrNotCA
+	}
+
+	if issuer.KeyUsage != 0 && issuer.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("%w: missing certificate signing usage", ErrKeyUsage)
+	}
+
+	// Intermediates between this issuer and the leaf.
+	if intermediates := index - 1; (issuer.MaxPathLen > 0 || issuer.MaxPathLenZero) &&
+		intermediates > issuer.MaxPathLen {
+		return fmt.Errorf(
+			"%w: %d intermediates follow, at most %d allowed", ErrPathLength, intermediates, issuer.MaxPathLen,
+		)
+	}
+
+	return nil
+}
+
+func checkExtKeyUsage(cert *x509.Certificate, accepted []x509.ExtKeyUsage) error {
+	if len(cert.ExtKeyUsage) == 0 || slices.Contains(accepted, x509.ExtKeyUsageAny) ||
+		slices.Contains(cert.ExtKeyUsage, x509.ExtKeyUsageAny) {
+		return nil
+	}
+
+	for _, usage := range accepted {
+		if slices.Contains(cert.ExtKeyUsage, usage) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: extended key usage %v, wanted one of %v", ErrKeyUsage, cert.ExtKeyUsage, accepted)
+}
+
+// VerifyRow checks the chain of a collection row against trusted roots: signatures and issuer links, validity
+// windows, basic constraints and key usages, name constraints, the key of the leaf, and optionally the hostname.
+//
+// Unlike x509.Certificate.Verify, the chain is used in the order of the row, and every check is run, so the
+// report lists all the failures at once. Use VerifyReport.Err to get them as a single error.
+//
+// If opts is nil, the default options are used.
+func VerifyRow(row CollectionRow, opts *VerifyOptions) *VerifyReport {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+
+	report := &VerifyReport{Chain: slices.Clone(row.Certificates())}
+
+	if len(report.Chain) == 0 {
+		report.fail(-1, ErrEmptyChain)
+		return report
+	}
+
+	if key := row.Key(); key != nil {
+		if err := MatchKey(key.Public(), report.Chain); err != nil {
+			report.fail(0, err)
+		}
+	}
+
+	if !report.anchor(opts.Roots) {
+		last := report.Chain[len(report.Chain)-1]
+		report.fail(len(report.Chain)-1, fmt.Errorf("%w: issuer %s not found", ErrUntrustedRoot, last.Issuer))
+	}
+
+	now := lo.CoalesceOrEmpty(opts.Clock, SystemClock).Now()
+	extKeyUsage := lo.Ternary(len(opts.ExtKeyUsage) > 0, opts.ExtKeyUsage, []x509.ExtKeyUsage{
+		x509.ExtKeyUsageServerAuth,
+	})
+
+	for pos, cert := range report.Chain {
+		if pos+1 < len(report.Chain) {
+			parent := report.Chain[pos+1]
+
+			switch {
+			case !bytes.Equal(cert.RawIssuer, parent.RawSubject):
+				report.fail(pos, fmt.Errorf("%w: issuer %s, next is %s", ErrBrokenLink, cert.Issuer, parent.Subject))
+			case parent.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) != nil:
+				report.fail(pos, fmt.Errorf("%w: invalid signature", ErrBrokenLink))
+			}
+		}
+
+		if pos > 0 {
+			if err := checkIssuer(cert, pos); err != nil {
+				report.fail(pos, err)
+			}
+		}
+
+		if err := checkValidity(cert, now); err != nil {
+			report.fail(pos, err)
+		}
+
+		if err := checkExtKeyUsage(cert, extKeyUsage); err != nil {
+			report.fail(pos, err)
+		}
+
+		if err := checkNameConstraints(report.Chain[pos+1:], cert); err != nil {
+			report.fail(pos, err)
+		}
+	}
+
+	if opts.Hostname != "" {
+		if err := report.Chain[0].VerifyHostname(opts.Hostname); err != nil {
+			report.fail(0, fmt.Errorf("%w: %w", ErrHostnameMismatch, err))
+		}
+	}
+
+	return report
+}
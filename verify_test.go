@@ -0,0 +1,325 @@
+package certdeck_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/a-novel-kit/certdeck"
+	"github.com/a-novel-kit/certdeck/clocktest"
+	testcerts "github.com/a-novel-kit/certdeck/internal/certs"
+)
+
+// newVerifyTestCert issues a certificate from the template. If parent is nil, the certificate is self-signed.
+func newVerifyTestCert(
+	t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey crypto.Signer,
+) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(raw)
+	require.NoError(t, err)
+
+	return cert, key
+}
+
+func TestVerifyRow(t *testing.T) {
+	chain := []*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain2Cert, testcerts.Chain3Cert}
+	withRoot := append(chain[:len(chain):len(chain)], testcerts.CACert)
+
+	// Chain 3 expires before the root.
+	validTime := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name string
+
+		certs []*x509.Certificate
+		key   crypto.Signer
+		opts  *certdeck.VerifyOptions
+
+		expectChain []*x509.Certificate
+		// Index of the failed certificate, for each expected error.
+		expectFailures map[int]error
+	}{
+		{
+			name:  "Valid",
+			certs: chain,
+			key:   testcerts.Chain1Key,
+			opts: &certdeck.VerifyOptions{
+				Roots:    []*x509.Certificate{testcerts.CACert},
+				Hostname: "www.example.com",
+				Clock:    clocktest.New(validTime),
+			},
+			expectChain: withRoot,
+		},
+		{
+			name:  "Valid/RootInChain",
+			certs: withRoot,
+			key:   testcerts.Chain1Key,
+			opts: &certdeck.VerifyOptions{
+				Roots: []*x509.Certificate{testcerts.CACert},
+				Clock: clocktest.New(validTime),
+			},
+			expectChain: withRoot,
+		},
+		{
+			name:  "Valid/IntermediateTrusted",
+			certs: withRoot,
+			key:   testcerts.Chain1Key,
+			opts: &certdeck.VerifyOptions{
+				Roots: []*x509.Certificate{testcerts.Chain2Cert},
+				Clock: clocktest.New(validTime),
+			},
+			expectChain: chain[:2],
+		},
+		{
+			name:  "Expired",
+			certs: chain,
+			key:   testcerts.Chain1Key,
+			opts: &certdeck.VerifyOptions{
+				Roots: []*x509.Certificate{testcerts.CACert},
+				Clock: clocktest.New(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			expectChain: withRoot,
+			expectFailures: map[int]error{
+				0: certdeck.ErrCertExpired,
+				1: certdeck.ErrCertExpired,
+				2: certdeck.ErrCertExpired,
+			},
+		},
+		{
+			name:  "NotYetValid",
+			certs: chain,
+			key:   testcerts.Chain1Key,
+			opts: &certdeck.VerifyOptions{
+				Roots: []*x509.Certificate{testcerts.CACert},
+				Clock: clocktest.New(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+			expectChain: withRoot,
+			expectFailures: map[int]error{
+				0: certdeck.ErrCertNotYetValid,
+				1: certdeck.ErrCertNotYetValid,
+				2: certdeck.ErrCertNotYetValid,
+				3: certdeck.ErrCertNotYetValid,
+			},
+		},
+		{
+			name:  "UntrustedRoot",
+			certs: chain,
+			key:   testcerts.Chain1Key,
+			opts: &certdeck.VerifyOptions{
+				Clock: clocktest.New(validTime),
+			},
+			expectChain:    chain,
+			expectFailures: map[int]error{2: certdeck.ErrUntrustedRoot},
+		},
+		{
+			name:  "BrokenLink",
+			certs: []*x509.Certificate{testcerts.Chain1Cert, testcerts.Chain3Cert},
+			key:   testcerts.Chain1Key,
+			opts: &certdeck.VerifyOptions{
+				Roots: []*x509.Certificate{testcerts.CACert},
+				Clock: clocktest.New(validTime),
+			},
+			expectChain: []*x509.Certificate{
+				testcerts.Chain1Cert, testcerts.Chain3Cert, testcerts.CACert,
+			},
+			expectFailures: map[int]error{0: certdeck.ErrBrokenLink},
+		},
+		{
+			name:  "KeyMismatch",
+			certs: chain,
+			key:   testcerts.Chain2Key,
+			opts: &certdeck.VerifyOptions{
+				Roots: []*x509.Certificate{testcerts.CACert},
+				Clock: clocktest.New(validTime),
+			},
+			expectChain:    withRoot,
+			expectFailures: map[int]error{0: certdeck.ErrCertKeyMismatch},
+		},
+		{
+			name:  "HostnameMismatch",
+			certs: chain,
+			key:   testcerts.Chain1Key,
+			opts: &certdeck.VerifyOptions{
+				Roots:    []*x509.Certificate{testcerts.CACert},
+				Hostname: "example.org",
+				Clock:    clocktest.New(validTime),
+			},
+			expectChain:    withRoot,
+			expectFailures: map[int]error{0: certdeck.ErrHostnameMismatch},
+		},
+		{
+			name:           "Empty",
+			opts:           &certdeck.VerifyOptions{Roots: []*x509.Certificate{testcerts.CACert}},
+			expectFailures: map[int]error{-1: certdeck.ErrEmptyChain},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			report := certdeck.VerifyRow(&certdeck.CollectionRowBase{
+				Certs:   testCase.certs,
+				CertKey: testCase.key,
+			}, testCase.opts)
+
+			if testCase.expectChain != nil {
+				require.NoError(t, certdeck.Match(testCase.expectChain, report.Chain))
+			}
+
+			require.Len(t, report.Failures, len(testCase.expectFailures), report.Err())
+			for _, failure := range report.Failures {
+				require.ErrorIs(t, failure, testCase.expectFailures[failure.Index], failure.Error())
+				require.ErrorIs(t, report.Err(), testCase.expectFailures[failure.Index])
+			}
+
+			require.Equal(t, len(testCase.expectFailures) == 0, report.Valid())
+			if report.Valid() {
+				require.NoError(t, report.Err())
+			}
+		})
+	}
+}
+
+func TestVerifyRowConstraints(t *testing.T) {
+	caUsage := x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+
+	root, rootKey := newVerifyTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "root"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              caUsage,
+		MaxPathLen:            1,
+		PermittedDNSDomains:   []string{"example.com"},
+	}, nil, nil)
+
+	intermediate, intermediateKey := newVerifyTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              caUsage,
+		MaxPathLenZero:        true,
+	}, root, rootKey)
+
+	subIntermediate, subIntermediateKey := newVerifyTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "sub intermediate"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              caUsage,
+	}, intermediate, intermediateKey)
+
+	notCA, notCAKey := newVerifyTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "not a CA"},
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+	}, root, rootKey)
+
+	newLeaf := func(parent *x509.Certificate, parentKey crypto.Signer, dnsName string) (*x509.Certificate, crypto.Signer) {
+		return newVerifyTestCert(t, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: dnsName},
+			DNSNames:    []string{dnsName},
+			KeyUsage:    x509.KeyUsageDigitalSignature,
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}, parent, parentKey)
+	}
+
+	validLeaf, validLeafKey := newLeaf(intermediate, intermediateKey, "www.example.com")
+	excludedLeaf, excludedLeafKey := newLeaf(intermediate, intermediateKey, "www.example.org")
+	deepLeaf, deepLeafKey := newLeaf(subIntermediate, subIntermediateKey, "deep.example.com")
+	notCALeaf, notCALeafKey := newLeaf(notCA, notCAKey, "www.example.com")
+
+	roots := []*x509.Certificate{root}
+
+	testCases := []struct {
+		name string
+
+		certs []*x509.Certificate
+		key   crypto.Signer
+		opts  *certdeck.VerifyOptions
+
+		expectFailures map[int]error
+	}{
+		{
+			name:  "Valid",
+			certs: []*x509.Certificate{validLeaf, intermediate},
+			key:   validLeafKey,
+			opts:  &certdeck.VerifyOptions{Roots: roots, Hostname: "www.example.com"},
+		},
+		{
+			name:           "NameConstraints",
+			certs:          []*x509.Certificate{excludedLeaf, intermediate},
+			key:            excludedLeafKey,
+			opts:           &certdeck.VerifyOptions{Roots: roots},
+			expectFailures: map[int]error{0: certdeck.ErrNameConstraint},
+		},
+		{
+			name:           "PathLength",
+			certs:          []*x509.Certificate{deepLeaf, subIntermediate, intermediate},
+			key:            deepLeafKey,
+			opts:           &certdeck.VerifyOptions{Roots: roots},
+			expectFailures: map[int]error{2: certdeck.ErrPathLength, 3: certdeck.ErrPathLength},
+		},
+		{
+			name:           "NotCA",
+			certs:          []*x509.Certificate{notCALeaf, notCA},
+			key:            notCALeafKey,
+			opts:           &certdeck.VerifyOptions{Roots: roots},
+			expectFailures: map[int]error{1: certdeck.ErrNotCA},
+		},
+		{
+			name:  "ExtKeyUsage",
+			certs: []*x509.Certificate{validLeaf, intermediate},
+			key:   validLeafKey,
+			opts: &certdeck.VerifyOptions{
+				Roots:       roots,
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			},
+			expectFailures: map[int]error{0: certdeck.ErrKeyUsage},
+		},
+		{
+			name:  "ExtKeyUsage/Any",
+			certs: []*x509.Certificate{validLeaf, intermediate},
+			key:   validLeafKey,
+			opts: &certdeck.VerifyOptions{
+				Roots:       roots,
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			report := certdeck.VerifyRow(&certdeck.CollectionRowBase{
+				Certs:   testCase.certs,
+				CertKey: testCase.key,
+			}, testCase.opts)
+
+			require.Equal(t, root, report.Root)
+			require.Len(t, report.Failures, len(testCase.expectFailures), report.Err())
+			for _, failure := range report.Failures {
+				require.ErrorIs(t, failure, testCase.expectFailures[failure.Index], failure.Error())
+			}
+		})
+	}
+}